@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/pkg/diff"
+	"gh-pr-commenter/pkg/findings"
+	"gh-pr-commenter/pkg/status"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// postAnnotations posts the result's findings that carry a file and line as check run annotations.
+// The check run's summary is the result's output, cut to what the checks API accepts with a pointer to commentURL.
+func postAnnotations(ctx context.Context, client *github.Client, owner, repo, sha, name, commentURL string, result *profileResult) error {
+	dir := config.GetConfig().RepoRelDir
+	var annotations []*github.CheckRunAnnotation
+	for _, f := range result.Findings {
+		if f.File == "" || f.Line == 0 {
+			continue
+		}
+		title := f.Rule
+		if f.Tool != "" {
+			title = f.Tool + ": " + f.Rule
+		}
+		annotations = append(annotations, &github.CheckRunAnnotation{
+			Path:            github.String(diff.RepoPath(dir, f.File)),
+			StartLine:       github.Int(f.Line),
+			EndLine:         github.Int(f.Line),
+			AnnotationLevel: github.String(annotationLevel(f.Severity)),
+			Title:           github.String(title),
+			Message:         github.String(f.Message),
+		})
+	}
+
+	state := status.StateFailure
+	if result.Passed {
+		state = status.StateSuccess
+	}
+	return status.NewCheckRunReporter(client, owner, repo, sha).Report(ctx, status.Status{
+		State:       state,
+		Context:     name,
+		Description: result.Description,
+		Summary:     status.TruncateSummary(result.Output, truncatedSummaryNote(commentURL)),
+		Annotations: annotations,
+	})
+}
+
+// truncatedSummaryNote points from a truncated check run summary to the full report
+func truncatedSummaryNote(commentURL string) string {
+	if commentURL == "" {
+		return "_The report is too long for a check run and was truncated._"
+	}
+	return fmt.Sprintf("_The report is too long for a check run and was truncated. See the [pull request comment](%s) for the full report._", commentURL)
+}
+
+// annotationLevel maps a severity onto the checks API's failure, warning and notice levels
+func annotationLevel(severity findings.Severity) string {
+	switch rank := severity.Rank(); {
+	case rank >= findings.Severity("error").Rank():
+		return "failure"
+	case rank >= findings.Severity("warning").Rank():
+		return "warning"
+	default:
+		return "notice"
+	}
+}
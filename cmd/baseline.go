@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/pkg/findings"
+)
+
+// Baseline records the findings in the report files matching patterns as the known findings of kind
+func Baseline(kind string, patterns []string) error {
+	// Recording a baseline doesn't talk to GitHub, so it runs outside PRs too
+	config.Load(kind)
+	result, err := readReport(kind, patterns)
+	if err != nil {
+		return err
+	}
+	if result.render == nil {
+		return fmt.Errorf("%s reports have no findings to record", kind)
+	}
+
+	path := config.GetConfig().BaselineFile
+	if path == "" {
+		path = config.DefaultBaselineFile
+	}
+	baseline, err := findings.LoadBaseline(path)
+	if err != nil {
+		return err
+	}
+	baseline.Record(kind, result.Findings)
+	if err := baseline.Save(path); err != nil {
+		return err
+	}
+	fmt.Printf("Recorded %d %s findings in %s\n", len(baseline.Kinds[kind]), kind, path)
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gh-pr-commenter/config"
+)
+
+// Env writes the CI system ghpc detected and the settings it reads from the environment to w,
+// with where each one comes from: set explicitly, detected from the CI system or not set
+func Env(w io.Writer) {
+	ci := config.DetectCI()
+	if ci == nil {
+		fmt.Fprintln(w, "CI: none detected")
+	} else {
+		fmt.Fprintf(w, "CI: %s\n", ci.Name)
+	}
+	for _, key := range config.CIKeys {
+		value, source := os.Getenv(key), "environment"
+		if value == "" && ci != nil {
+			value, source = ci.Settings[key], ci.Name
+		}
+		if value == "" {
+			source = "not set"
+		}
+		fmt.Fprintf(w, "%s=%s (%s)\n", key, value, source)
+	}
+	// The token itself is never printed
+	if os.Getenv("GITHUB_TOKEN") != "" {
+		fmt.Fprintln(w, "GITHUB_TOKEN is set")
+	} else {
+		fmt.Fprintln(w, "GITHUB_TOKEN is not set")
+	}
+}
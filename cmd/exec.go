@@ -1,15 +1,17 @@
 package cmd
 
 import (
-	"bytes"
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
 	"gh-pr-commenter/config"
+	outputpkg "gh-pr-commenter/pkg/output"
 	"gh-pr-commenter/pkg/status"
 
 	"github.com/google/go-github/v41/github"
@@ -19,7 +21,7 @@ import (
 
 const maxCommentLength = 55000
 
-func ExecuteAndComment(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, prNumber string, command string) error {
+func ExecuteAndComment(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, prNumber string, command string) (err error) {
 	logger := config.GetLogger()
 	cmdArgs := strings.Fields(command)
 	if len(cmdArgs) == 0 {
@@ -30,65 +32,260 @@ func ExecuteAndComment(ctx context.Context, client *github.Client, graphqlClient
 	outputExitCode := 1
 	config.Init(cmdName)
 	cnf := config.GetConfig()
-	err := status.PostCommitStatus(ctx, client, owner, repo, cnf.HeadCommit, "pending", cnf.GHStatusContext)
+	err = postStatus(ctx, client, owner, repo, cnf.HeadCommit, status.StatePending, cmdName, "", cnf.StatusTargetURL, cnf.GHStatusContext)
 	if err != nil {
 		return fmt.Errorf("error posting commit status: %w", err)
 	}
-	cmd := exec.CommandContext(ctx, cmdName, cmdArgs...)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err = cmd.Run()
+	// Statuses must still be posted once the command context is cancelled
+	statusCtx := context.WithoutCancel(ctx)
+	// A pending status left behind blocks merges, so any way out of the run without a final status posts error
+	finalPosted := false
+	defer func() {
+		if finalPosted {
+			return
+		}
+		reason := ""
+		r := recover()
+		if r != nil {
+			reason = fmt.Sprintf("ghpc crashed: %v", r)
+		} else if err != nil {
+			reason = fmt.Sprintf("ghpc failed: %v", err)
+		} else {
+			return
+		}
+		postErr := postStatus(statusCtx, client, owner, repo, cnf.HeadCommit, status.StateError, cmdName, fmt.Sprintf("%s: %s", cmdName, reason), cnf.StatusTargetURL, cnf.GHStatusContext)
+		if postErr != nil {
+			logger.Error("Error posting error status", zap.Error(postErr))
+		}
+		if r != nil {
+			panic(r)
+		}
+	}()
+	runCtx := ctx
+	if cnf.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cnf.CommandTimeout)
+		defer cancel()
+	}
 
-	output := out.String()
+	cmd := exec.Command(cmdName, cmdArgs...)
+	// Stream the output to the console while it is captured
+	var console io.Writer = os.Stdout
+	if cnf.OutputTimestamps {
+		console = outputpkg.NewTimestampWriter(os.Stdout)
+	}
+	capture := outputpkg.NewCapture(cnf.OutputMemoryLimit, cnf.TmpGhpcDir, console)
+	defer capture.Close()
+	cmd.Stdout = capture.StdoutWriter()
+	cmd.Stderr = capture.StderrWriter()
+	start := time.Now()
+	runErr := runCommand(runCtx, cmd)
+	elapsed := time.Since(start).Round(time.Second)
 
+	normalizeOpts := outputpkg.NormalizeOptions{ColorToDiff: cnf.ColorToDiff}
+	var result *profileResult
+	killed := runCtx.Err()
+	failure := classifyRunFailure(cmdName, runErr, killed, elapsed)
+	prof, err := profileFor(cnf.Profile, cmdName)
 	if err != nil {
-		logger.Error("Error running command", zap.Error(err))
-		output += fmt.Sprintf("\nError running command: %v\n", err)
-	}
-	if (output == "" || strings.Contains(output, "passed")) && err == nil {
-		outputExitCode = 0
-		if cmdName == "tflint" {
-			output = fmt.Sprintf("%s passed.\n\nNo output was generated.", cmdName)
-		}
-	}
-	output = fmt.Sprintf("\n%s\n%s\n\n---\n", cnf.ProjectRunDetails, output)
-	newFilename := fmt.Sprintf("%s/.output-%s.md", cnf.TmpGhpcDir ,cmdName)
-	
-	fileExists := false
-	if _, err := os.Stat(newFilename); err == nil {
-		fileExists = true
-	}
-	
-	var file *os.File
-	if fileExists {
-		file, err = os.OpenFile(newFilename, os.O_APPEND|os.O_WRONLY, 0644)
+		return err
+	}
+	if failure == nil {
+		if prof != nil {
+			// Profiles parse the output, so it's read into memory; plain output is streamed into the output file below
+			combined, stdout, stderr, err := capture.ReadAll()
+			if err != nil {
+				return err
+			}
+			result, err = prof(commandOutput{
+				Combined: outputpkg.Normalize(combined, normalizeOpts),
+				Stdout:   outputpkg.Normalize(stdout, normalizeOpts),
+				Stderr:   outputpkg.Normalize(stderr, normalizeOpts),
+				Err:      runErr,
+			})
+			if err != nil {
+				return fmt.Errorf("error applying output profile: %w", err)
+			}
+		}
+	}
+
+	// The combined output file holds the profile's rendering, or the captured output between prefix and suffix
+	output, prefix, suffix := "", "", ""
+	description := ""
+	var guard *destroyGuardResult
+	if result != nil {
+		err = processFindings(ctx, client, graphqlClient, owner, repo, prNumber, profileName(cnf.Profile, cmdName), cnf, result)
 		if err != nil {
-			return fmt.Errorf("error opening file: %w", err)
+			return err
+		}
+		output = result.Output
+		description = result.Description
+		if result.Passed {
+			outputExitCode = 0
+		}
+		if result.Plan != nil && cnf.DestroyGuard {
+			guard, err = checkDestroyGuard(ctx, client, owner, repo, prNumber, cnf, result.Plan)
+			if err != nil {
+				return fmt.Errorf("error checking destroy guard: %w", err)
+			}
+			output = guard.Banner + output
 		}
-	} else {
-		file, err = os.Create(newFilename)
+	}
+	if failure != nil {
+		logger.Error("Command could not run", zap.String("reason", failure.Reason), zap.Duration("elapsed", elapsed), zap.Error(runErr))
+		prefix = failure.Banner()
+	} else if runErr != nil && (result == nil || !result.Passed) {
+		logger.Error("Error running command", zap.Error(runErr))
+		suffix = fmt.Sprintf("\nError running command: %v\n", runErr)
+	}
+	if result == nil && prof != nil {
+		// The profile's default template doesn't fence the output, so output it didn't render,
+		// e.g. of a run that timed out, is fenced like the terraform profile's fallback
+		prefix += rawOutputStart
+		suffix = rawOutputEnd + suffix
+	}
+	writeCombined := func(w io.Writer) error {
+		if result != nil {
+			_, err := io.WriteString(w, prefix+output+suffix)
+			return err
+		}
+		return writeNormalized(w, capture.Combined, normalizeOpts, prefix, suffix)
+	}
+	if result == nil && failure == nil && runErr == nil {
+		empty, passed, err := outputpkg.ScanNormalized(capture.Combined, normalizeOpts, "passed")
 		if err != nil {
-			return fmt.Errorf("error creating file: %w", err)
+			return err
+		}
+		if empty || passed {
+			outputExitCode = 0
+			if cmdName == "tflint" {
+				writeCombined = func(w io.Writer) error {
+					_, err := fmt.Fprintf(w, "%s passed.\n\nNo output was generated.", cmdName)
+					return err
+				}
+			}
+		}
+	}
+	sections := map[string]func(w io.Writer) error{
+		outputpkg.FileName(cnf.TmpGhpcDir, cmdName): writeCombined,
+		outputpkg.StreamFileName(cnf.TmpGhpcDir, cmdName, "stdout"): func(w io.Writer) error {
+			return writeNormalized(w, capture.Stdout, normalizeOpts, "", "")
+		},
+		outputpkg.StreamFileName(cnf.TmpGhpcDir, cmdName, "stderr"): func(w io.Writer) error {
+			return writeNormalized(w, capture.Stderr, normalizeOpts, "", "")
+		},
+	}
+	for filename, write := range sections {
+		err := appendOutputFile(filename, cnf.ProjectRunDetails, write)
+		if err != nil {
+			return err
 		}
 	}
-	defer file.Close()
 
-	if _, err := file.WriteString(output); err != nil {
-		return fmt.Errorf("error writing to file: %w", err)
+	if failure != nil {
+		description = fmt.Sprintf("%s: %s", cmdName, failure.Reason)
+		err = postStatus(statusCtx, client, owner, repo, cnf.HeadCommit, status.StateError, cmdName, description, cnf.StatusTargetURL, cnf.GHStatusContext)
+		if err != nil {
+			return fmt.Errorf("error posting error status: %w", err)
+		}
+		finalPosted = true
+		if killed != nil && killed != context.DeadlineExceeded {
+			return fmt.Errorf("command interrupted: %w", killed)
+		}
+		return nil
 	}
 
 	time.Sleep(5 * time.Second)
+	if guard != nil {
+		err = status.NewCommitStatusReporter(client, owner, repo, cnf.HeadCommit).Report(statusCtx, status.Status{
+			State:       guard.State,
+			Context:     cnf.DestroyGuardContext,
+			Description: guard.Description,
+			TargetURL:   cnf.StatusTargetURL,
+		})
+		if err != nil {
+			return fmt.Errorf("error posting destroy guard status: %w", err)
+		}
+	}
 	if outputExitCode == 0 {
-		err = status.PostCommitStatus(ctx, client, owner, repo, cnf.HeadCommit, "success", cnf.GHStatusContext)
+		err = postStatus(statusCtx, client, owner, repo, cnf.HeadCommit, status.StateSuccess, cmdName, description, cnf.StatusTargetURL, cnf.GHStatusContext)
 		if err != nil {
 			return fmt.Errorf("error posting success status: %w", err)
 		}
+		finalPosted = true
 		return nil
 	}
-	err = status.PostCommitStatus(ctx, client, owner, repo, cnf.HeadCommit, "failure", cnf.GHStatusContext)
+	err = postStatus(statusCtx, client, owner, repo, cnf.HeadCommit, status.StateFailure, cmdName, description, cnf.StatusTargetURL, cnf.GHStatusContext)
 	if err != nil {
 		return fmt.Errorf("error posting failure status: %w", err)
 	}
+	finalPosted = true
+	return nil
+}
+
+// postStatus posts state for command, describing it with the STATUS_DESCRIPTION template.
+// The template's summary is the run's description, or the default one for state.
+func postStatus(ctx context.Context, client *github.Client, owner, repo, sha string, state status.State, command, description, targetURL, statusContext string) error {
+	cnf := config.GetConfig()
+	if description == "" {
+		description = state.DefaultDescription()
+	}
+	description, err := status.RenderDescription(cnf.StatusDescription, status.DescriptionData{
+		State:     state,
+		Command:   command,
+		Project:   cnf.ProjectName,
+		Workspace: cnf.Workspace,
+		Summary:   description,
+	})
+	if err != nil {
+		return err
+	}
+	return status.NewCommitStatusReporter(client, owner, repo, sha).Report(ctx, status.Status{
+		State:       state,
+		Context:     statusContext,
+		Description: description,
+		TargetURL:   targetURL,
+	})
+}
+
+// appendOutputFile appends a project's section to a captured output file, creating it if needed.
+// The section's content is written by write, so captured output can be streamed into the file.
+func appendOutputFile(filename, details string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	// Buffered, as the normalized output is written line by line
+	w := bufio.NewWriter(file)
+	if _, err := fmt.Fprintf(w, "\n%s\n", details); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if err := write(w); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n\n---\n"); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
 	return nil
 }
+
+// writeNormalized streams the normalized output captured in buf to w, between prefix and suffix
+func writeNormalized(w io.Writer, buf *outputpkg.Buffer, opts outputpkg.NormalizeOptions, prefix, suffix string) error {
+	if _, err := io.WriteString(w, prefix); err != nil {
+		return err
+	}
+	nw := outputpkg.NewNormalizeWriter(w, opts)
+	if _, err := buf.WriteTo(nw); err != nil {
+		return err
+	}
+	if err := nw.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, suffix)
+	return err
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"time"
+)
+
+// runFailure is why a command couldn't run to completion, as opposed to the command finding problems.
+// Run failures are reported with the error state, findings with the failure state.
+type runFailure struct {
+	// Reason is a short description for the commit status, e.g. "command not found"
+	Reason string
+	// Detail explains the failure in the comment
+	Detail string
+}
+
+// classifyRunFailure returns the failure behind a command's run, or nil when the command ran and exited by itself.
+// killed is the error of the context the command was run with.
+func classifyRunFailure(cmdName string, runErr, killed error, elapsed time.Duration) *runFailure {
+	switch {
+	case errors.Is(killed, context.DeadlineExceeded):
+		return &runFailure{Reason: "timed out", Detail: fmt.Sprintf("Command timed out and was killed after %s.", elapsed)}
+	case killed != nil:
+		return &runFailure{Reason: "cancelled", Detail: fmt.Sprintf("Command was cancelled and was killed after %s.", elapsed)}
+	case runErr == nil:
+		return nil
+	case errors.Is(runErr, exec.ErrNotFound):
+		return &runFailure{Reason: "command not found", Detail: fmt.Sprintf("`%s` was not found on the PATH.", cmdName)}
+	case errors.Is(runErr, fs.ErrPermission):
+		return &runFailure{Reason: "permission denied", Detail: fmt.Sprintf("`%s` could not be executed: %v", cmdName, runErr)}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		// The exit code is -1 when the process was terminated by a signal
+		if exitErr.ExitCode() == -1 {
+			return &runFailure{Reason: "killed by a signal", Detail: fmt.Sprintf("Command was terminated by a signal (%v) after %s.", exitErr, elapsed)}
+		}
+		return nil
+	}
+	return &runFailure{Reason: "could not be started", Detail: fmt.Sprintf("`%s` could not be started: %v", cmdName, runErr)}
+}
+
+// Banner is the comment banner that marks the output as a failed run rather than the tool's findings
+func (f *runFailure) Banner() string {
+	return fmt.Sprintf("> [!CAUTION]\n> **The command could not run: %s.** This is a problem with the environment, not a finding of the tool.\n>\n> %s\n\n", f.Reason, f.Detail)
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/internal"
+	"gh-pr-commenter/pkg/diff"
+	"gh-pr-commenter/pkg/findings"
+	"gh-pr-commenter/pkg/store"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+)
+
+// narrowing drops findings that aren't reported
+type narrowing func(fs []findings.Finding) []findings.Finding
+
+// processFindings applies the configured handling to a result with findings.
+// The findings are stored for the head commit, narrowed down by the baseline of kind and the --only-changed filter,
+// compared with the base commit's, and finally posted as inline review comments.
+func processFindings(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo, prNumber, kind string, cnf *config.Config, result *profileResult) error {
+	if result.render == nil {
+		return nil
+	}
+	resultStore, err := store.Open(cnf.ResultStore)
+	if err != nil {
+		return err
+	}
+	key := store.Key{SHA: cnf.HeadCommit, Kind: kind, Project: cnf.ProjectIdentifier}
+	if err := resultStore.Save(key, result.Findings); err != nil {
+		return err
+	}
+
+	var narrowings []narrowing
+	if cnf.BaselineFile != "" {
+		baseline, err := findings.LoadBaseline(cnf.BaselineFile)
+		if err != nil {
+			return err
+		}
+		// Compared against all findings, as the baseline's entries aren't narrowed to the changed files
+		compared := baseline.Compare(kind, result.Findings)
+		result.header += fmt.Sprintf("**Baseline:** %d new, %d fixed, %d known findings not reported (`%s`)\n\n", len(compared.New), compared.Fixed, compared.Known, cnf.BaselineFile)
+		narrowings = append(narrowings, func(fs []findings.Finding) []findings.Finding {
+			return baseline.Compare(kind, fs).New
+		})
+	}
+
+	var changes diff.Changes
+	pullNum, _ := strconv.Atoi(prNumber)
+	if cnf.StatusOnly && (cnf.OnlyChanged != "" || cnf.ReviewComments) {
+		config.GetLogger().Warn("No pull request, so findings aren't narrowed to changed files or posted as review comments")
+	} else if cnf.OnlyChanged != "" || cnf.ReviewComments {
+		keep, err := onlyChangedFilter(cnf.OnlyChanged, cnf.RepoRelDir)
+		if err != nil {
+			return err
+		}
+		if pullNum == 0 {
+			return fmt.Errorf("error converting PR number to int: %s", prNumber)
+		}
+		changes, err = internal.ListChangedFilesWithRetry(ctx, client, owner, repo, pullNum)
+		if err != nil {
+			return err
+		}
+		if keep != nil {
+			narrowings = append(narrowings, func(fs []findings.Finding) []findings.Finding {
+				var kept []findings.Finding
+				for _, f := range fs {
+					if keep(changes, f) {
+						kept = append(kept, f)
+					}
+				}
+				return kept
+			})
+		}
+	}
+	current := narrow(narrowings, result.Findings)
+	if cnf.CompareBase {
+		current, err = compareBase(ctx, client, owner, repo, pullNum, cnf, resultStore, key, narrowings, result, current)
+		if err != nil {
+			return err
+		}
+	}
+	if len(narrowings) > 0 || cnf.CompareBase {
+		if err := result.applyFindings(current); err != nil {
+			return err
+		}
+	}
+
+	if cnf.ReviewComments && !cnf.StatusOnly {
+		err = postReview(ctx, client, graphqlClient, owner, repo, pullNum, cnf.HeadCommit, cnf.GHStatusContext, changes, result)
+		if err != nil {
+			return fmt.Errorf("error posting review: %w", err)
+		}
+	}
+	return nil
+}
+
+// narrow applies narrowings in order
+func narrow(narrowings []narrowing, fs []findings.Finding) []findings.Finding {
+	for _, n := range narrowings {
+		fs = n(fs)
+	}
+	return fs
+}
+
+// compareBase returns the current findings the base commit's run didn't have, and summarizes the delta in the result's header.
+// The base run's findings are narrowed down the same way as the current ones.
+// Without stored base results, all current findings are returned.
+func compareBase(ctx context.Context, client *github.Client, owner, repo string, pullNum int, cnf *config.Config, resultStore store.Store, key store.Key, narrowings []narrowing, result *profileResult, current []findings.Finding) ([]findings.Finding, error) {
+	baseSHA := cnf.BaseCommit
+	if baseSHA == "" {
+		if pullNum == 0 {
+			return nil, fmt.Errorf("error looking up the base commit: no PR number or BASE_COMMIT")
+		}
+		pr, _, err := client.PullRequests.Get(ctx, owner, repo, pullNum)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up the base commit: %w", err)
+		}
+		baseSHA = pr.GetBase().GetSHA()
+	}
+
+	key.SHA = baseSHA
+	base, err := resultStore.Load(key)
+	if errors.Is(err, store.ErrNotFound) {
+		result.header += fmt.Sprintf("**Compared to base:** no results stored for `%s`, showing all findings\n\n", shortSHA(baseSHA))
+		return current, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	delta := findings.Compare(narrow(narrowings, base), current)
+	result.header += fmt.Sprintf("**Compared to base `%s`:** %s\n\n", shortSHA(baseSHA), delta)
+	return delta.New, nil
+}
+
+// shortSHA abbreviates a commit SHA the way GitHub displays it
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// onlyChangedFilter returns the filter for an --only-changed mode, or nil when findings aren't filtered.
+// Finding paths are relative to the project directory dir, the PR's to the repository root.
+func onlyChangedFilter(mode, dir string) (func(changes diff.Changes, f findings.Finding) bool, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case "files":
+		return func(changes diff.Changes, f findings.Finding) bool {
+			return changes.HasFile(diff.RepoPath(dir, f.File))
+		}, nil
+	case "lines":
+		return func(changes diff.Changes, f findings.Finding) bool {
+			return changes.HasAddedLine(diff.RepoPath(dir, f.File), f.Line)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown --only-changed mode: %s (expected files or lines)", mode)
+	}
+}
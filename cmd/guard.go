@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/pkg/status"
+	"gh-pr-commenter/pkg/terraform"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// destroyGuardResult is the outcome of checking a plan for protected resources being destroyed
+type destroyGuardResult struct {
+	Banner      string
+	State       status.State
+	Description string
+}
+
+// checkDestroyGuard looks for destroyed or replaced protected resources in plan.
+// A violation fails the guard unless the pull request carries the override label.
+func checkDestroyGuard(ctx context.Context, client *github.Client, owner, repo, prNumber string, cnf *config.Config, plan *terraform.Plan) (*destroyGuardResult, error) {
+	guard := terraform.Guard{Patterns: cnf.DestroyGuardPatterns}
+	violations := guard.Violations(plan)
+	if len(violations) == 0 {
+		return &destroyGuardResult{State: status.StateSuccess, Description: "No protected resources destroyed"}, nil
+	}
+
+	// Without a PR there's no label to override the guard with
+	overridden := false
+	if !cnf.StatusOnly {
+		var err error
+		overridden, err = hasLabel(ctx, client, owner, repo, prNumber, cnf.DestroyGuardOverrideLabel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := &destroyGuardResult{
+		Banner:      terraform.GuardBanner(violations, cnf.DestroyGuardOverrideLabel, overridden),
+		State:       status.StateFailure,
+		Description: fmt.Sprintf("%d protected resource(s) destroyed or replaced", len(violations)),
+	}
+	if overridden {
+		result.State = status.StateSuccess
+		result.Description = fmt.Sprintf("Destroy allowed by the %s label", cnf.DestroyGuardOverrideLabel)
+	}
+	return result, nil
+}
+
+// hasLabel reports whether the pull request carries label
+func hasLabel(ctx context.Context, client *github.Client, owner, repo, prNumber, label string) (bool, error) {
+	if label == "" {
+		return false, nil
+	}
+	pullNum, err := strconv.Atoi(prNumber)
+	if err != nil {
+		return false, fmt.Errorf("error converting PR number: %w", err)
+	}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		labels, resp, err := client.Issues.ListLabelsByIssue(ctx, owner, repo, pullNum, opts)
+		if err != nil {
+			return false, fmt.Errorf("error listing labels: %w", err)
+		}
+		for _, l := range labels {
+			if l.GetName() == label {
+				return true, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
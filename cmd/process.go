@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// killGracePeriod is how long a cancelled command gets to exit before it is killed
+const killGracePeriod = 10 * time.Second
+
+// runCommand runs cmd in its own process group until it exits or ctx is done.
+// On cancellation the group is asked to terminate and, if it is still running
+// after killGracePeriod, killed.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	terminateProcessGroup(cmd)
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(killGracePeriod):
+		killProcessGroup(cmd)
+		return <-done
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/pkg/findings"
+	"gh-pr-commenter/pkg/terraform"
+	"gh-pr-commenter/pkg/trivy"
+)
+
+// commandOutput is the normalized output of a finished command
+type commandOutput struct {
+	Combined string
+	Stdout   string
+	Stderr   string
+	// Err is the error the command exited with, if any
+	Err error
+}
+
+// profileResult is what a profile derives from a command's output
+type profileResult struct {
+	// Output replaces the captured output section and is rendered as markdown
+	Output string
+	// Description is used as the commit status description
+	Description string
+	// Passed decides between the success and failure commit states
+	Passed bool
+	// Plan is the parsed terraform plan, if the output contained one
+	Plan *terraform.Plan
+	// Findings are the issues parsed from a linter or scanner's output
+	Findings []findings.Finding
+	// tool names the linter or scanner the findings came from
+	tool string
+	// header is rendered above the findings, e.g. to summarize a baseline comparison
+	header string
+	// render renders a subset of Findings the same way Output was rendered
+	render func(subset []findings.Finding) (string, error)
+}
+
+// renderFindings renders subset below the result's header
+func (r *profileResult) renderFindings(subset []findings.Finding) (string, error) {
+	output, err := r.render(subset)
+	if err != nil {
+		return "", err
+	}
+	return r.header + output, nil
+}
+
+// applyFindings replaces the result's findings and re-derives its output and status from them
+func (r *profileResult) applyFindings(subset []findings.Finding) error {
+	policy, err := findings.ParsePolicy(config.GetConfig().FailOn)
+	if err != nil {
+		return err
+	}
+	output, err := r.renderFindings(subset)
+	if err != nil {
+		return err
+	}
+	r.Findings = subset
+	r.Output = output
+	r.Description = fmt.Sprintf("%s: %s", r.tool, findings.Summary(subset))
+	r.Passed = len(policy.Failing(subset)) == 0
+	return nil
+}
+
+// profile turns a command's output into a rendered result.
+// A nil result means the profile doesn't apply and the output is handled as plain text.
+type profile func(out commandOutput) (*profileResult, error)
+
+// profiles are the built-in output profiles, selected by PROFILE or the command name
+var profiles = map[string]profile{
+	"terraform": terraformProfile,
+	"tflint":    tflintProfile,
+	"trivy":     trivyProfile,
+}
+
+// profileName returns the name of the profile applied to cmdName's output
+func profileName(name, cmdName string) string {
+	if name == "" {
+		return cmdName
+	}
+	return name
+}
+
+// profileFor returns the profile named by name, falling back to the one for cmdName
+func profileFor(name, cmdName string) (profile, error) {
+	if name == "" {
+		return profiles[cmdName], nil
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile: %s", name)
+	}
+	return p, nil
+}
+
+// rawOutputStart and rawOutputEnd fence output a profile couldn't render as a diff, like the default template does
+const (
+	rawOutputStart = "<details><summary>Show Output</summary>\n\n```diff\n"
+	rawOutputEnd   = "\n```\n</details>\n"
+)
+
+// terraformProfile renders `terraform plan` or `terraform show -json` output as a plan summary.
+// Other terraform output is fenced as a diff so it renders like the default template.
+func terraformProfile(out commandOutput) (*profileResult, error) {
+	planOutput := out.Combined
+	if strings.HasPrefix(strings.TrimSpace(out.Stdout), "{") {
+		planOutput = out.Stdout
+	}
+	plan, err := terraform.ParsePlan(planOutput)
+	if err != nil {
+		return &profileResult{
+			Output: rawOutputStart + out.Combined + rawOutputEnd,
+			Passed: out.Err == nil,
+		}, nil
+	}
+	// terraform plan -detailed-exitcode exits with 2 when the plan has changes
+	var exitErr *exec.ExitError
+	passed := out.Err == nil || (errors.As(out.Err, &exitErr) && exitErr.ExitCode() == 2)
+	return &profileResult{
+		Output:      plan.Markdown(),
+		Description: plan.StatusDescription(),
+		Passed:      passed,
+		Plan:        plan,
+	}, nil
+}
+
+// tflintProfile renders the output of `tflint --format=json` as a table of findings.
+// Text output is left to the default handling.
+func tflintProfile(out commandOutput) (*profileResult, error) {
+	if !strings.HasPrefix(strings.TrimSpace(out.Stdout), "{") {
+		return nil, nil
+	}
+	parsed, err := findings.ParseTflintJSON([]byte(out.Stdout))
+	if err != nil {
+		return nil, nil
+	}
+	return findingsResult("tflint", parsed)
+}
+
+// trivyProfile renders the output of `trivy --format json` with the built-in trivy template.
+// Output rendered by trivy itself, e.g. with --format template, keeps the default handling.
+func trivyProfile(out commandOutput) (*profileResult, error) {
+	if !strings.HasPrefix(strings.TrimSpace(out.Stdout), "{") {
+		return nil, nil
+	}
+	report, err := trivy.ParseReport([]byte(out.Stdout))
+	if err != nil {
+		return nil, nil
+	}
+	return trivyResult(report)
+}
+
+// trivyResult renders a trivy report and decides the status with the configured fail-on policy
+func trivyResult(report *trivy.Report) (*profileResult, error) {
+	result := &profileResult{
+		tool: "trivy",
+		render: func(subset []findings.Finding) (string, error) {
+			keep := map[findings.Finding]bool{}
+			for _, f := range subset {
+				keep[f] = true
+			}
+			return report.Filter(func(f findings.Finding) bool { return keep[f] }).Markdown()
+		},
+	}
+	if err := result.applyFindings(report.Findings()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// findingsResult renders findings and decides the status with the configured fail-on policy
+func findingsResult(tool string, parsed []findings.Finding) (*profileResult, error) {
+	result := &profileResult{
+		tool: tool,
+		render: func(subset []findings.Finding) (string, error) {
+			return findings.Markdown(tool, subset), nil
+		},
+	}
+	if err := result.applyFindings(parsed); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/internal"
+
+	"github.com/google/go-github/v41/github"
+	"go.uber.org/zap"
+)
+
+// ResolvePullRequest looks up the open PR of HEAD_COMMIT, or of HEAD_BRANCH, when PULL_NUM isn't set.
+// PULL_MATCH picks among several PRs. Without a PR, the run switches to status-only mode.
+func ResolvePullRequest(ctx context.Context, client *github.Client, cnf *config.Config) error {
+	if cnf.PullNum != "" || cnf.StatusOnly {
+		return nil
+	}
+	logger := config.GetLogger()
+	pulls, err := internal.ListOpenPullRequests(ctx, client, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.HeadCommit, cnf.HeadBranch)
+	if err != nil {
+		return err
+	}
+	pull, err := pickPullRequest(pulls, cnf.PullMatch)
+	if err != nil {
+		return err
+	}
+	if pull == nil {
+		logger.Info("No open pull request found, only posting commit statuses", zap.String("commit", cnf.HeadCommit), zap.String("branch", cnf.HeadBranch))
+		config.SetPullNum("")
+		return nil
+	}
+	logger.Info("Resolved pull request", zap.Int("number", pull.GetNumber()), zap.String("commit", cnf.HeadCommit), zap.String("branch", cnf.HeadBranch))
+	config.SetPullNum(strconv.Itoa(pull.GetNumber()))
+	return nil
+}
+
+// pickPullRequest picks one of pulls by match: the newest or oldest, or an error when there are several
+func pickPullRequest(pulls []*github.PullRequest, match string) (*github.PullRequest, error) {
+	if len(pulls) == 0 {
+		return nil, nil
+	}
+	sort.Slice(pulls, func(i, j int) bool { return pulls[i].GetNumber() < pulls[j].GetNumber() })
+	switch match {
+	case config.PullMatchNewest, "":
+		return pulls[len(pulls)-1], nil
+	case config.PullMatchOldest:
+		return pulls[0], nil
+	case config.PullMatchError:
+		if len(pulls) > 1 {
+			numbers := make([]int, len(pulls))
+			for i, pull := range pulls {
+				numbers[i] = pull.GetNumber()
+			}
+			return nil, fmt.Errorf("%d open pull requests match: %v, set PULL_NUM to pick one", len(pulls), numbers)
+		}
+		return pulls[0], nil
+	default:
+		return nil, fmt.Errorf("unknown pull request match %q: use %s, %s or %s", match, config.PullMatchNewest, config.PullMatchOldest, config.PullMatchError)
+	}
+}
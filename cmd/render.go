@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/pkg/comments"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// Render formats for a comment preview
+const (
+	RenderMarkdown = "markdown"
+	RenderHTML     = "html"
+)
+
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/github-markdown-css/5.5.1/github-markdown.min.css">
+<style>.markdown-body { max-width: 980px; margin: 0 auto; padding: 45px; } hr.ghpc-part { margin: 48px 0; }</style>
+</head>
+<body class="markdown-body">
+%s
+</body>
+</html>
+`
+
+// Render writes the comment parts ghpc comment would post for a captured output file to w.
+// command defaults to the one the output file was captured for, e.g. tflint for .output-tflint.md.
+// The html format converts each part with GitHub's Markdown API, so the page looks as the comments will.
+// It needs network access; without GITHUB_TOKEN the API is called unauthenticated, at a lower rate limit.
+func Render(ctx context.Context, client *github.Client, command, inputFile, templateFile, format string, w io.Writer) error {
+	if format != RenderMarkdown && format != RenderHTML {
+		return fmt.Errorf("unknown render format %q: use %s or %s", format, RenderMarkdown, RenderHTML)
+	}
+	if command == "" {
+		command = capturedCommand(inputFile)
+		if command == "" {
+			return fmt.Errorf("can't tell the command from %s, pass it as an argument", inputFile)
+		}
+	}
+	config.Load(strings.Fields(command)[0])
+	cnf := config.GetConfig()
+	if templateFile == "" {
+		templateFile = cnf.TemplateFilename
+	}
+
+	combined, err := os.ReadFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("error reading output file: %w", err)
+	}
+	stdout, stderr, err := comments.ReadStreams(inputFile)
+	if err != nil {
+		return err
+	}
+	templateContent, err := comments.ReadTemplate(templateFile, comments.TemplateCommand(command, cnf.Profile))
+	if err != nil {
+		return err
+	}
+	parts, err := comments.RenderParts(templateContent, command, string(combined), stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	if format == RenderMarkdown {
+		for i, part := range parts {
+			if len(parts) > 1 {
+				fmt.Fprintf(w, "<!-- ghpc render: comment %d of %d -->\n", i+1, len(parts))
+			}
+			fmt.Fprintf(w, "%s\n", part)
+		}
+		return nil
+	}
+
+	opts := &github.MarkdownOptions{Mode: "gfm"}
+	if cnf.BaseRepoOwner != "" && cnf.BaseRepoName != "" {
+		// Issue and commit references link into the repository
+		opts.Context = cnf.BaseRepoOwner + "/" + cnf.BaseRepoName
+	}
+	var body strings.Builder
+	for i, part := range parts {
+		html, _, err := client.Markdown(ctx, part, opts)
+		if err != nil {
+			return fmt.Errorf("error converting comment to HTML: %w", err)
+		}
+		if i > 0 {
+			body.WriteString("<hr class=\"ghpc-part\">\n")
+		}
+		body.WriteString(html)
+	}
+	_, err = fmt.Fprintf(w, htmlPage, strings.Fields(command)[0]+" output", body.String())
+	return err
+}
+
+// capturedCommand returns the command an output file was captured for, or "" for other files
+func capturedCommand(outputFile string) string {
+	name := filepath.Base(outputFile)
+	if !strings.HasPrefix(name, ".output-") || !strings.HasSuffix(name, ".md") {
+		return ""
+	}
+	command := strings.TrimSuffix(strings.TrimPrefix(name, ".output-"), ".md")
+	if command == "" || strings.Contains(command, ".") {
+		return ""
+	}
+	return command
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/internal"
+	"gh-pr-commenter/pkg/comments"
+	"gh-pr-commenter/pkg/findings"
+	"gh-pr-commenter/pkg/junit"
+	"gh-pr-commenter/pkg/status"
+	"gh-pr-commenter/pkg/trivy"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+)
+
+// reportFile is a report file read from disk
+type reportFile struct {
+	Path string
+	Data []byte
+}
+
+// reportKinds render report files into a result, keyed by report kind
+var reportKinds = map[string]func(files []reportFile) (*profileResult, error){
+	"tflint": findingsReport("tflint", findings.ParseTflintJSON),
+	"trivy":  trivyReport,
+	"sarif":  sarifReport,
+	"junit":  junitReport,
+}
+
+// findingsReport returns a report kind that parses each file with parse and renders the findings as a table
+func findingsReport(tool string, parse func(data []byte) ([]findings.Finding, error)) func(files []reportFile) (*profileResult, error) {
+	return func(files []reportFile) (*profileResult, error) {
+		var all []findings.Finding
+		for _, file := range files {
+			parsed, err := parse(file.Data)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing %s: %w", file.Path, err)
+			}
+			all = append(all, parsed...)
+		}
+		return findingsResult(tool, all)
+	}
+}
+
+// sarifReport parses SARIF logs and titles the report after the tools that produced them
+func sarifReport(files []reportFile) (*profileResult, error) {
+	var all []findings.Finding
+	for _, file := range files {
+		parsed, err := findings.ParseSARIF(file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file.Path, err)
+		}
+		all = append(all, parsed...)
+	}
+	title := "sarif"
+	if tools := findings.Tools(all); len(tools) > 0 {
+		title = strings.Join(tools, ", ")
+	}
+	return findingsResult(title, all)
+}
+
+// junitReport summarizes JUnit XML test reports and fails when any test failed
+func junitReport(files []reportFile) (*profileResult, error) {
+	var suites []junit.Suite
+	for _, file := range files {
+		parsed, err := junit.Parse(file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file.Path, err)
+		}
+		suites = append(suites, parsed...)
+	}
+	total := junit.Total(suites)
+	return &profileResult{
+		Output:      junit.Markdown(suites),
+		Description: fmt.Sprintf("junit: %s", total),
+		Passed:      total.Failed == 0,
+	}, nil
+}
+
+// trivyReport merges trivy JSON reports and renders them with the built-in trivy template
+func trivyReport(files []reportFile) (*profileResult, error) {
+	merged := &trivy.Report{}
+	for _, file := range files {
+		report, err := trivy.ParseReport(file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file.Path, err)
+		}
+		merged.Merge(report)
+	}
+	return trivyResult(merged)
+}
+
+// Report parses the report files matching patterns and posts the findings as a PR comment with a commit status
+func Report(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, prNumber string, kind string, patterns []string) error {
+	config.Init(kind)
+	result, err := readReport(kind, patterns)
+	if err != nil {
+		return err
+	}
+	cnf := config.GetConfig()
+	err = processFindings(ctx, client, graphqlClient, owner, repo, prNumber, kind, cnf, result)
+	if err != nil {
+		return err
+	}
+	commentURL, err := postReport(ctx, client, graphqlClient, owner, repo, prNumber, kind, result)
+	if err != nil {
+		return err
+	}
+	if cnf.Annotations {
+		err = postAnnotations(ctx, client, owner, repo, cnf.HeadCommit, cnf.GHStatusContext, commentURL, result)
+		if err != nil {
+			return fmt.Errorf("error posting annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+// readReport renders the report files of kind matching patterns
+func readReport(kind string, patterns []string) (*profileResult, error) {
+	render, ok := reportKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown report kind: %s", kind)
+	}
+
+	paths, err := expandPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	var files []reportFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading report file: %w", err)
+		}
+		files = append(files, reportFile{Path: path, Data: data})
+	}
+	return render(files)
+}
+
+// expandPatterns expands glob patterns into the files they match
+func expandPatterns(patterns []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no report files match %s", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// postReport posts a rendered report as a PR comment, split into parts if needed, and sets the commit status.
+// It returns the URL of the report's first comment, which is empty without a PR.
+func postReport(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, prNumber string, kind string, result *profileResult) (string, error) {
+	cnf := config.GetConfig()
+	title := fmt.Sprintf("## %s report", kind)
+	parts := comments.SplitMessage(fmt.Sprintf("%s\n%s", cnf.ProjectRunDetails, result.Output))
+	commentURL := ""
+	for i, part := range parts {
+		// Without a PR, the report only sets the status
+		if cnf.StatusOnly {
+			break
+		}
+		identifier := fmt.Sprintf("<!-- ghpc-report: %s Part #%d -->", cnf.ProjectIdentifier, i+1)
+		comment, err := internal.UpsertCommentBody(ctx, client, graphqlClient, owner, repo, prNumber, fmt.Sprintf("%s\n%s %s", title, part, identifier), title, identifier)
+		if err != nil {
+			return "", fmt.Errorf("error upserting comment: %w", err)
+		}
+		if i == 0 {
+			commentURL = comment.GetHTMLURL()
+		}
+	}
+
+	targetURL := cnf.StatusTargetURL
+	if commentURL != "" {
+		targetURL = commentURL
+	}
+	state := status.StateFailure
+	if result.Passed {
+		state = status.StateSuccess
+	}
+	return commentURL, postStatus(ctx, client, owner, repo, cnf.HeadCommit, state, kind, result.Description, targetURL, cnf.GHStatusContext)
+}
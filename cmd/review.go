@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/internal"
+	"gh-pr-commenter/pkg/diff"
+	"gh-pr-commenter/pkg/findings"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+)
+
+// reviewMarker identifies the review threads posted for a status context, so later runs can resolve them
+func reviewMarker(statusContext string) string {
+	return fmt.Sprintf("<!-- ghpc-review: %s -->", statusContext)
+}
+
+// postReview posts the result's findings on lines in the PR diff as inline review comments.
+// The ghpc review threads of previous runs are resolved first.
+// The result's output is re-rendered with the findings outside the diff, which stay in the summary comment.
+func postReview(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, pullNum int, sha, statusContext string, changes diff.Changes, result *profileResult) error {
+	marker := reviewMarker(statusContext)
+	if err := internal.ResolveReviewThreads(ctx, graphqlClient, owner, repo, pullNum, marker); err != nil {
+		return err
+	}
+
+	dir := config.GetConfig().RepoRelDir
+	var comments []*github.DraftReviewComment
+	var outside []findings.Finding
+	for _, f := range result.Findings {
+		file := diff.RepoPath(dir, f.File)
+		if f.Line == 0 || !changes.IsCommentable(file, f.Line) {
+			outside = append(outside, f)
+			continue
+		}
+		comments = append(comments, &github.DraftReviewComment{
+			Path: github.String(file),
+			Line: github.Int(f.Line),
+			Side: github.String("RIGHT"),
+			Body: github.String(fmt.Sprintf("**%s** `%s`: %s\n\n%s", f.Severity, f.Rule, f.Message, marker)),
+		})
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+
+	err := internal.CreateReview(ctx, client, owner, repo, pullNum, &github.PullRequestReviewRequest{
+		CommitID: github.String(sha),
+		Body:     github.String(fmt.Sprintf("%s\n\n%s", result.Description, marker)),
+		Event:    github.String("COMMENT"),
+		Comments: comments,
+	})
+	if err != nil {
+		return err
+	}
+
+	output, err := result.renderFindings(outside)
+	if err != nil {
+		return err
+	}
+	result.Output = fmt.Sprintf("%s\n_%d findings on changed lines were posted as review comments._\n", output, len(comments))
+	return nil
+}
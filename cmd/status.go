@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gh-pr-commenter/pkg/status"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// SetStatus posts a commit status on sha. An empty description is the default one for state.
+func SetStatus(ctx context.Context, client *github.Client, owner, repo, sha, state, statusContext, description, targetURL string) error {
+	parsed, err := status.ParseState(state)
+	if err != nil {
+		return err
+	}
+	return status.NewCommitStatusReporter(client, owner, repo, sha).Report(ctx, status.Status{
+		State:       parsed,
+		Context:     statusContext,
+		Description: description,
+		TargetURL:   targetURL,
+	})
+}
+
+// GetStatus writes the combined status of sha and the latest status of each context to w.
+// With statusContext, only that context's state is written, or "missing" when it has no status.
+func GetStatus(ctx context.Context, client *github.Client, owner, repo, sha, statusContext string, w io.Writer) error {
+	combined, err := status.GetCombinedStatus(ctx, client, owner, repo, sha)
+	if err != nil {
+		return err
+	}
+	if statusContext != "" {
+		state := "missing"
+		for _, s := range combined.Statuses {
+			if s.GetContext() == statusContext {
+				state = s.GetState()
+				break
+			}
+		}
+		_, err = fmt.Fprintln(w, state)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%s (%d contexts)\n", combined.GetState(), combined.GetTotalCount()); err != nil {
+		return err
+	}
+	for _, s := range combined.Statuses {
+		if _, err := fmt.Fprintf(w, "%-8s %s: %s\n", s.GetState(), s.GetContext(), s.GetDescription()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReconcileStatus resolves the pending statuses on sha whose context starts with prefix and that haven't changed for olderThan.
+// They are left behind by runs that were killed before posting a final status, and are set to error.
+// It returns the resolved contexts.
+func ReconcileStatus(ctx context.Context, client *github.Client, owner, repo, sha, prefix string, olderThan time.Duration, targetURL string) ([]string, error) {
+	combined, err := status.GetCombinedStatus(ctx, client, owner, repo, sha)
+	if err != nil {
+		return nil, err
+	}
+	reporter := status.NewCommitStatusReporter(client, owner, repo, sha)
+	var reconciled []string
+	for _, s := range combined.Statuses {
+		if s.GetState() != string(status.StatePending) || !strings.HasPrefix(s.GetContext(), prefix) {
+			continue
+		}
+		if time.Since(s.GetUpdatedAt()) < olderThan {
+			continue
+		}
+		err := reporter.Report(ctx, status.Status{
+			State:       status.StateError,
+			Context:     s.GetContext(),
+			Description: fmt.Sprintf("Stale: the run stopped without a result (pending since %s)", s.GetUpdatedAt().UTC().Format(time.RFC3339)),
+			TargetURL:   targetURL,
+		})
+		if err != nil {
+			return reconciled, err
+		}
+		reconciled = append(reconciled, s.GetContext())
+	}
+	return reconciled, nil
+}
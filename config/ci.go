@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CIKeys are the settings a CI system can provide, in the order ghpc env prints them
+var CIKeys = []string{
+	"HEAD_COMMIT", "HEAD_BRANCH", "BASE_REPO_OWNER", "BASE_REPO_NAME", "PULL_NUM", "PROJECT_NAME", "WORKSPACE", "REPO_REL_DIR", "PULL_URL", "STATUS_TARGET_URL",
+}
+
+// CI is the CI system ghpc runs in
+type CI struct {
+	Name string
+	// Settings are the ghpc settings read from the CI system's environment, keyed by ghpc variable.
+	// Variables set explicitly take precedence.
+	Settings map[string]string
+}
+
+type ciDetector struct {
+	name     string
+	detect   func() bool
+	settings func() map[string]string
+}
+
+// ciDetectors are tried in order; Atlantis comes first as it may itself run in another CI system's environment
+var ciDetectors = []ciDetector{
+	{"Atlantis", func() bool { return os.Getenv("ATLANTIS_TERRAFORM_VERSION") != "" || os.Getenv("PLANFILE") != "" }, atlantisSettings},
+	{"GitHub Actions", func() bool { return os.Getenv("GITHUB_ACTIONS") == "true" }, githubActionsSettings},
+	{"Buildkite", func() bool { return os.Getenv("BUILDKITE") == "true" }, buildkiteSettings},
+	{"Jenkins", func() bool { return os.Getenv("JENKINS_URL") != "" }, jenkinsSettings},
+}
+
+var (
+	// repoURLPattern matches the owner and name in git@github.com:owner/repo.git, https://github.com/owner/repo
+	// and https://github.com/owner/repo/pull/12
+	repoURLPattern = regexp.MustCompile(`[:/]([^/:]+)/([^/]+?)(?:\.git)?(?:/pull/\d+)?/?$`)
+	pullRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/`)
+)
+
+// DetectCI returns the CI system ghpc runs in, or nil when it isn't one ghpc knows
+func DetectCI() *CI {
+	for _, d := range ciDetectors {
+		if !d.detect() {
+			continue
+		}
+		settings := map[string]string{}
+		for key, value := range d.settings() {
+			if value != "" {
+				settings[key] = value
+			}
+		}
+		return &CI{Name: d.name, Settings: settings}
+	}
+	return nil
+}
+
+// atlantisSettings are the Atlantis variables, which ghpc's names were taken from
+func atlantisSettings() map[string]string {
+	settings := map[string]string{}
+	for _, key := range []string{"HEAD_COMMIT", "BASE_REPO_OWNER", "BASE_REPO_NAME", "PULL_NUM", "PROJECT_NAME", "WORKSPACE", "REPO_REL_DIR", "PULL_URL"} {
+		settings[key] = os.Getenv(key)
+	}
+	return settings
+}
+
+// githubActionsSettings reads the repository from GITHUB_REPOSITORY and the PR from the event payload.
+// For pull_request events GITHUB_SHA is a merge commit, so the PR's head commit is used instead.
+func githubActionsSettings() map[string]string {
+	settings := map[string]string{}
+	if owner, name, ok := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/"); ok {
+		settings["BASE_REPO_OWNER"] = owner
+		settings["BASE_REPO_NAME"] = name
+	}
+
+	var event struct {
+		PullRequest *struct {
+			Number  int    `json:"number"`
+			HTMLURL string `json:"html_url"`
+			Head    struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+		Issue *struct {
+			Number      int `json:"number"`
+			PullRequest *struct {
+				HTMLURL string `json:"html_url"`
+			} `json:"pull_request"`
+		} `json:"issue"`
+	}
+	if content, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH")); err == nil {
+		json.Unmarshal(content, &event)
+	}
+	switch {
+	case event.PullRequest != nil:
+		settings["HEAD_COMMIT"] = event.PullRequest.Head.SHA
+		settings["PULL_NUM"] = strconv.Itoa(event.PullRequest.Number)
+		settings["PULL_URL"] = event.PullRequest.HTMLURL
+	case event.Issue != nil && event.Issue.PullRequest != nil:
+		// A PR comment runs on the default branch, so GITHUB_SHA isn't the PR's commit
+		settings["PULL_NUM"] = strconv.Itoa(event.Issue.Number)
+		settings["PULL_URL"] = event.Issue.PullRequest.HTMLURL
+	default:
+		settings["HEAD_COMMIT"] = os.Getenv("GITHUB_SHA")
+		ref := os.Getenv("GITHUB_REF")
+		if m := pullRefPattern.FindStringSubmatch(ref); m != nil {
+			settings["PULL_NUM"] = m[1]
+		} else if strings.HasPrefix(ref, "refs/heads/") {
+			settings["HEAD_BRANCH"] = strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	return settings
+}
+
+// buildkiteSettings reads the build's commit and repository, and the PR of pull request builds
+func buildkiteSettings() map[string]string {
+	settings := map[string]string{
+		"HEAD_COMMIT":       os.Getenv("BUILDKITE_COMMIT"),
+		"HEAD_BRANCH":       os.Getenv("BUILDKITE_BRANCH"),
+		"STATUS_TARGET_URL": os.Getenv("BUILDKITE_BUILD_URL"),
+	}
+	if owner, name, ok := parseRepoURL(os.Getenv("BUILDKITE_REPO")); ok {
+		settings["BASE_REPO_OWNER"] = owner
+		settings["BASE_REPO_NAME"] = name
+	}
+	// BUILDKITE_PULL_REQUEST is "false" for branch builds
+	if pullNum := os.Getenv("BUILDKITE_PULL_REQUEST"); pullNum != "false" {
+		settings["PULL_NUM"] = pullNum
+	}
+	return settings
+}
+
+// jenkinsSettings reads the variables the Git plugin and, for PR builds, the GitHub Branch Source plugin set
+func jenkinsSettings() map[string]string {
+	settings := map[string]string{
+		"HEAD_COMMIT":       os.Getenv("GIT_COMMIT"),
+		"PULL_NUM":          os.Getenv("CHANGE_ID"),
+		"PULL_URL":          os.Getenv("CHANGE_URL"),
+		"STATUS_TARGET_URL": os.Getenv("BUILD_URL"),
+	}
+	// BRANCH_NAME is e.g. PR-12 in PR builds
+	if settings["PULL_NUM"] == "" {
+		settings["HEAD_BRANCH"] = os.Getenv("BRANCH_NAME")
+	}
+	repoURL := os.Getenv("CHANGE_URL")
+	if repoURL == "" {
+		repoURL = os.Getenv("GIT_URL")
+	}
+	if owner, name, ok := parseRepoURL(repoURL); ok {
+		settings["BASE_REPO_OWNER"] = owner
+		settings["BASE_REPO_NAME"] = name
+	}
+	return settings
+}
+
+// parseRepoURL returns the owner and name of a repository's clone or PR URL
+func parseRepoURL(url string) (owner, name string, ok bool) {
+	m := repoURLPattern.FindStringSubmatch(url)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
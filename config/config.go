@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -14,6 +16,20 @@ const (
 	DefaultWorkspace    = "default"
 	DefaultTemplateFile = "template.md"
 	DefaultTmpGhpcDir   = "/tmp/ghpc"
+
+	DefaultOutputMemoryLimit = 8 << 20
+	DefaultDestroyGuardLabel = "ghpc-allow-destroy"
+	DefaultBaselineFile      = ".ghpc-baseline.json"
+
+	// DefaultReconcileOlderThan keeps ghpc status reconcile from resolving the statuses of runs still in progress
+	DefaultReconcileOlderThan = time.Hour
+)
+
+// How a PR is picked when several open ones match HEAD_COMMIT or HEAD_BRANCH
+const (
+	PullMatchNewest = "newest"
+	PullMatchOldest = "oldest"
+	PullMatchError  = "error"
 )
 
 type Config struct {
@@ -29,6 +45,41 @@ type Config struct {
 	ProjectRunDetails string
 	ProjectIdentifier string
 	TmpGhpcDir        string
+	KeepOutput        bool
+	CommandTimeout    time.Duration
+	OutputTimestamps  bool
+	OutputMemoryLimit int
+	ColorToDiff       bool
+	Profile           string
+	FailOn            string
+	Annotations       bool
+	ReviewComments    bool
+	OnlyChanged       string
+	BaselineFile      string
+	ResultStore       string
+	CompareBase       bool
+	BaseCommit        string
+	StatusTargetURL   string
+	StatusDescription string
+	// StatusContextPrefix starts the status contexts ghpc posts, e.g. "ghpc/"
+	StatusContextPrefix string
+	// DryRun prints the writes to GitHub instead of sending them
+	DryRun bool
+	// CI names the CI system the settings were detected from, if any
+	CI string
+	// HeadBranch is looked up to find the PR when HEAD_COMMIT isn't part of one
+	HeadBranch string
+	// PullMatch picks the PR when several match: newest, oldest or error
+	PullMatch string
+	// StatusOnly posts commit statuses but no comments, for runs without a PR
+	StatusOnly bool
+	// RepoRelDir is the project's directory relative to the repository root, which tools report paths relative to
+	RepoRelDir string
+
+	DestroyGuard              bool
+	DestroyGuardPatterns      []string
+	DestroyGuardOverrideLabel string
+	DestroyGuardContext       string
 }
 
 var (
@@ -36,25 +87,95 @@ var (
 	logger *zap.Logger
 )
 
+// requiredKeys must be set to comment on a PR
+var requiredKeys = []string{
+	"HEAD_COMMIT", "BASE_REPO_OWNER", "BASE_REPO_NAME", "PULL_NUM", "GITHUB_TOKEN",
+}
+
+// statusKeys must be set to post commit statuses, which don't need a PR
+var statusKeys = []string{
+	"HEAD_COMMIT", "BASE_REPO_OWNER", "BASE_REPO_NAME", "GITHUB_TOKEN",
+}
+
+// Init loads the config for cmdName and requires the settings to comment on a PR
 func Init(cmdName string) {
+	Load(cmdName)
+	ValidateConfig()
+}
+
+// InitStatus loads the config for cmdName and requires the settings to post commit statuses
+func InitStatus(cmdName string) {
+	Load(cmdName)
+	validateKeys(statusKeys)
+}
+
+// Load loads the config for cmdName without requiring any settings, for commands that don't talk to GitHub
+func Load(cmdName string) {
 	viper.AutomaticEnv()
 
 	viper.SetDefault("PROJECT_NAME", DefaultProjectName)
 	viper.SetDefault("WORKSPACE", DefaultWorkspace)
 	viper.SetDefault("TEMPLATE_FILENAME", DefaultTemplateFile)
 	viper.SetDefault("TMP_GHPC_DIR", DefaultTmpGhpcDir)
+	viper.SetDefault("OUTPUT_MEMORY_LIMIT", DefaultOutputMemoryLimit)
+	viper.SetDefault("DESTROY_GUARD_OVERRIDE_LABEL", DefaultDestroyGuardLabel)
+	viper.SetDefault("PULL_MATCH", PullMatchNewest)
+	// The CI system's settings fill in the ones that aren't set explicitly
+	ci := DetectCI()
+	if ci != nil {
+		for key, value := range ci.Settings {
+			viper.SetDefault(key, value)
+		}
+	}
 
 	config = &Config{
-		HeadCommit:       viper.GetString("HEAD_COMMIT"),
-		ProjectName:      viper.GetString("PROJECT_NAME"),
-		GHStatusContext:  viper.GetString("GH_STATUS_CONTEXT"),
-		Workspace:        viper.GetString("WORKSPACE"),
-		BaseRepoOwner:    viper.GetString("BASE_REPO_OWNER"),
-		BaseRepoName:     viper.GetString("BASE_REPO_NAME"),
-		PullNum:          viper.GetString("PULL_NUM"),
-		TemplateFilename: viper.GetString("TEMPLATE_FILENAME"),
-		GithubToken:      viper.GetString("GITHUB_TOKEN"),
-		TmpGhpcDir:       viper.GetString("TMP_GHPC_DIR"),
+		HeadCommit:        viper.GetString("HEAD_COMMIT"),
+		ProjectName:       viper.GetString("PROJECT_NAME"),
+		GHStatusContext:   viper.GetString("GH_STATUS_CONTEXT"),
+		Workspace:         viper.GetString("WORKSPACE"),
+		BaseRepoOwner:     viper.GetString("BASE_REPO_OWNER"),
+		BaseRepoName:      viper.GetString("BASE_REPO_NAME"),
+		PullNum:           viper.GetString("PULL_NUM"),
+		TemplateFilename:  viper.GetString("TEMPLATE_FILENAME"),
+		GithubToken:       viper.GetString("GITHUB_TOKEN"),
+		TmpGhpcDir:        viper.GetString("TMP_GHPC_DIR"),
+		KeepOutput:        viper.GetBool("KEEP_OUTPUT"),
+		CommandTimeout:    viper.GetDuration("COMMAND_TIMEOUT"),
+		OutputTimestamps:  viper.GetBool("OUTPUT_TIMESTAMPS"),
+		OutputMemoryLimit: viper.GetInt("OUTPUT_MEMORY_LIMIT"),
+		ColorToDiff:       viper.GetBool("COLOR_TO_DIFF"),
+		Profile:           viper.GetString("PROFILE"),
+		FailOn:            viper.GetString("FAIL_ON"),
+		Annotations:       viper.GetBool("ANNOTATIONS"),
+		ReviewComments:    viper.GetBool("REVIEW_COMMENTS"),
+		OnlyChanged:       viper.GetString("ONLY_CHANGED"),
+		BaselineFile:      viper.GetString("BASELINE_FILE"),
+		ResultStore:       viper.GetString("RESULT_STORE"),
+		CompareBase:       viper.GetBool("COMPARE_BASE"),
+		BaseCommit:        viper.GetString("BASE_COMMIT"),
+		StatusTargetURL:   viper.GetString("STATUS_TARGET_URL"),
+		StatusDescription: viper.GetString("STATUS_DESCRIPTION"),
+		DryRun:            viper.GetBool("DRY_RUN"),
+		HeadBranch:        viper.GetString("HEAD_BRANCH"),
+		PullMatch:         viper.GetString("PULL_MATCH"),
+		StatusOnly:        viper.GetBool("STATUS_ONLY"),
+		RepoRelDir:        viper.GetString("REPO_REL_DIR"),
+
+		DestroyGuard:              viper.GetBool("DESTROY_GUARD"),
+		DestroyGuardPatterns:      splitList(viper.GetString("DESTROY_GUARD_PATTERNS")),
+		DestroyGuardOverrideLabel: viper.GetString("DESTROY_GUARD_OVERRIDE_LABEL"),
+	}
+	if ci != nil {
+		config.CI = ci.Name
+	}
+	if config.StatusTargetURL == "" {
+		config.StatusTargetURL = runURL()
+	}
+	if config.ResultStore == "" {
+		config.ResultStore = filepath.Join(config.TmpGhpcDir, "results")
+	}
+	if len(config.DestroyGuardPatterns) > 0 {
+		config.DestroyGuard = true
 	}
 
 	if config.ProjectName != "" && config.Workspace != "" {
@@ -63,33 +184,50 @@ func Init(cmdName string) {
 	}
 
 	if config.GHStatusContext != "" && config.ProjectName != "" {
+		config.StatusContextPrefix = config.GHStatusContext + "/"
+		config.DestroyGuardContext = config.GHStatusContext + "/destroy-guard: " + config.ProjectName
 		config.GHStatusContext = config.GHStatusContext + "/" + cmdName + ": " + config.ProjectName
 	} else {
+		config.StatusContextPrefix = "ghpc/"
+		config.DestroyGuardContext = "ghpc/destroy-guard"
+		// Each project has its own guard, as a project's protected resources don't depend on the others
+		if config.ProjectName != "" {
+			config.DestroyGuardContext += ": " + config.ProjectName
+		}
 		config.GHStatusContext = "ghpc" + "/" + cmdName
 	}
 
-	// Log the environment variables right before validation
-	fmt.Println("HEAD_COMMIT:", config.HeadCommit)
-	fmt.Println("BASE_REPO_OWNER:", config.BaseRepoOwner)
-	fmt.Println("BASE_REPO_NAME:", config.BaseRepoName)
-	fmt.Println("PULL_NUM:", config.PullNum)
-	fmt.Println("GITHUB_TOKEN:", config.GithubToken)
-
-	ValidateConfig()
 	initLogger()
 }
 
 func ValidateConfig() {
-	requiredKeys := []string{
-		"HEAD_COMMIT", "BASE_REPO_OWNER", "BASE_REPO_NAME", "PULL_NUM", "GITHUB_TOKEN",
-	}
+	validateKeys(requiredKeys)
+}
+
+// validateKeys exits when any of keys isn't set
+func validateKeys(keys []string) {
+	// Logged to stderr, as the status commands print their result to stdout.
+	// The token is never logged, only whether it's set.
+	logger.Info("Validating settings",
+		zap.String("HEAD_COMMIT", config.HeadCommit),
+		zap.String("BASE_REPO_OWNER", config.BaseRepoOwner),
+		zap.String("BASE_REPO_NAME", config.BaseRepoName),
+		zap.String("PULL_NUM", config.PullNum),
+		zap.Bool("GITHUB_TOKEN set", config.GithubToken != ""),
+	)
 
 	missingKeys := []string{}
-	for _, key := range requiredKeys {
+	for _, key := range keys {
+		// A dry run doesn't write to GitHub, so it can run without a token
+		if key == "GITHUB_TOKEN" && config.DryRun {
+			continue
+		}
+		// Without a PR, only commit statuses are posted
+		if key == "PULL_NUM" && config.StatusOnly {
+			continue
+		}
 		if viper.GetString(key) == "" {
 			missingKeys = append(missingKeys, key)
-		} else {
-			log.Printf("Environment variable %s is set to: %s", key, viper.GetString(key))
 		}
 	}
 
@@ -106,6 +244,40 @@ func initLogger() {
 	}
 }
 
+// splitList splits a comma-separated setting into its trimmed, non-empty values
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// runURL links to the CI run: the GitHub Actions run, or the PR when running in Atlantis, which has no page per run
+func runURL() string {
+	server, repository, runID := viper.GetString("GITHUB_SERVER_URL"), viper.GetString("GITHUB_REPOSITORY"), viper.GetString("GITHUB_RUN_ID")
+	if server != "" && repository != "" && runID != "" {
+		return fmt.Sprintf("%s/%s/actions/runs/%s", server, repository, runID)
+	}
+	return viper.GetString("PULL_URL")
+}
+
+// SetPullNum sets the PR resolved for the run, which later loads of the config keep.
+// An empty pullNum means there is no PR and switches to status-only mode.
+func SetPullNum(pullNum string) {
+	if pullNum == "" {
+		viper.Set("STATUS_ONLY", true)
+	} else {
+		viper.Set("PULL_NUM", pullNum)
+	}
+	if config != nil {
+		config.PullNum = viper.GetString("PULL_NUM")
+		config.StatusOnly = viper.GetBool("STATUS_ONLY")
+	}
+}
+
 func GetConfig() *Config {
 	return config
 }
@@ -30,13 +30,20 @@ func UpsertComment(ctx context.Context, client *github.Client, graphqlClient *gr
 	if err != nil {
 		return fmt.Errorf("error reading comment file: %v", err)
 	}
+	_, err = UpsertCommentBody(ctx, client, graphqlClient, owner, repo, prNumber, message, title, identifier)
+	return err
+}
+
+// UpsertCommentBody handles creating or updating comments on the specified PR from an in-memory message.
+// It returns the created comment.
+func UpsertCommentBody(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, prNumber string, message, title, identifier string) (*github.IssueComment, error) {
 	pullNum, err := strconv.Atoi(prNumber)
 	if err != nil {
-		return fmt.Errorf("error converting PR number: %v", err)
+		return nil, fmt.Errorf("error converting PR number: %v", err)
 	}
 	comments, err := ListCommentsWithRetry(ctx, client, owner, repo, pullNum)
 	if err != nil {
-		return fmt.Errorf("error listing comments: %v", err)
+		return nil, fmt.Errorf("error listing comments: %v", err)
 	}
 
 	existingComments := filterCommentsByTitleAndIdentifier(comments, title, identifier)
@@ -44,20 +51,20 @@ func UpsertComment(ctx context.Context, client *github.Client, graphqlClient *gr
 	// Always hide previous comments
 	err = minimizeComments(ctx, graphqlClient, existingComments)
 	if err != nil {
-		return fmt.Errorf("error minimizing comments: %v", err)
+		return nil, fmt.Errorf("error minimizing comments: %v", err)
 	}
 
 	// Always create new parts with unique content to avoid collapsing
 	timestamp := time.Now().Format(time.RFC3339)
 	uniquePart := fmt.Sprintf("%s\n<!-- Unique ID: %s -->", message, timestamp)
 	comment := &github.IssueComment{Body: &uniquePart}
-	err = createCommentWithRetry(ctx, client, owner, repo, pullNum, comment)
+	created, err := createCommentWithRetry(ctx, client, owner, repo, pullNum, comment)
 	if err != nil {
-		return fmt.Errorf("error creating comment: %v", err)
+		return nil, fmt.Errorf("error creating comment: %v", err)
 	}
 
 	fmt.Println("Comment upserted successfully.")
-	return nil
+	return created, nil
 }
 
 // ListCommentsWithRetry lists comments with retry logic and pagination
@@ -86,17 +93,18 @@ func ListCommentsWithRetry(ctx context.Context, client *github.Client, owner, re
 }
 
 // createCommentWithRetry creates a comment with retry logic
-func createCommentWithRetry(ctx context.Context, client *github.Client, owner, repo string, pullNum int, comment *github.IssueComment) error {
+func createCommentWithRetry(ctx context.Context, client *github.Client, owner, repo string, pullNum int, comment *github.IssueComment) (*github.IssueComment, error) {
 	var err error
 	for i := 0; i < maxRetries; i++ {
-		_, _, err = client.Issues.CreateComment(ctx, owner, repo, pullNum, comment)
+		var created *github.IssueComment
+		created, _, err = client.Issues.CreateComment(ctx, owner, repo, pullNum, comment)
 		if err == nil {
-			return nil
+			return created, nil
 		}
 		fmt.Printf("Error creating comment (attempt %d/%d): %v\n", i+1, maxRetries, err)
 		time.Sleep(time.Second * time.Duration(1<<i)) // Exponential backoff
 	}
-	return fmt.Errorf("error creating comment after %d retries: %w", maxRetries, err)
+	return nil, fmt.Errorf("error creating comment after %d retries: %w", maxRetries, err)
 }
 
 // filterCommentsByTitleAndIdentifier filters comments to find those that match the given title and identifier
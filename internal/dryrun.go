@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+	"golang.org/x/oauth2"
+)
+
+var (
+	commentsPath      = regexp.MustCompile(`/issues/(\d+)/comments$`)
+	commentPath       = regexp.MustCompile(`/issues/comments/(\d+)$`)
+	statusesPath      = regexp.MustCompile(`/statuses/([^/]+)$`)
+	checkRunsPath     = regexp.MustCompile(`/check-runs$`)
+	checkRunPath      = regexp.MustCompile(`/check-runs/(\d+)$`)
+	reviewsPath       = regexp.MustCompile(`/pulls/(\d+)/reviews$`)
+	emptyListSuffixes = []string{"comments", "statuses", "files", "labels", "reviews", "pulls"}
+)
+
+// DryRunTransport sends GitHub API reads and prints the writes instead of sending them, answering them as GitHub would
+type DryRunTransport struct {
+	Base http.RoundTripper
+	Out  io.Writer
+	// Offline answers reads with empty results instead of sending them, for dry runs without a token
+	Offline bool
+}
+
+// NewDryRunGitHubClients returns REST and GraphQL clients that print writes to out instead of sending them.
+// Reads are sent when GITHUB_TOKEN is set; without it, they're answered as if the PR had no comments or statuses.
+func NewDryRunGitHubClients(ctx context.Context, out io.Writer) (*github.Client, *graphql.Client) {
+	transport := &DryRunTransport{Base: http.DefaultTransport, Out: out, Offline: true}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		transport.Base = oauth2.NewClient(ctx, ts).Transport
+		transport.Offline = false
+	}
+	httpClient := &http.Client{Transport: transport}
+	return github.NewClient(httpClient), graphql.NewClient("https://api.github.com/graphql", graphql.WithHTTPClient(httpClient))
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *DryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		return t.graphql(req, body)
+	}
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		if t.Offline {
+			return offlineResponse(req), nil
+		}
+		return t.Base.RoundTrip(req)
+	}
+	return t.write(req, body), nil
+}
+
+// write prints a REST write and answers it with the request, which is what GitHub echoes for the writes ghpc sends
+func (t *DryRunTransport) write(req *http.Request, body []byte) *http.Response {
+	fields := map[string]interface{}{}
+	json.Unmarshal(body, &fields)
+	field := func(name string) string {
+		if v, ok := fields[name]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	code := http.StatusOK
+	p := req.URL.Path
+	switch {
+	case req.Method == http.MethodPost && commentsPath.MatchString(p):
+		code = http.StatusCreated
+		fmt.Fprintf(t.Out, "[dry-run] Would create a comment on #%s:\n%s\n[dry-run] End of comment\n", commentsPath.FindStringSubmatch(p)[1], field("body"))
+	case req.Method == http.MethodPatch && commentPath.MatchString(p):
+		fmt.Fprintf(t.Out, "[dry-run] Would update comment %s:\n%s\n[dry-run] End of comment\n", commentPath.FindStringSubmatch(p)[1], field("body"))
+	case req.Method == http.MethodDelete && commentPath.MatchString(p):
+		code = http.StatusNoContent
+		fmt.Fprintf(t.Out, "[dry-run] Would delete comment %s\n", commentPath.FindStringSubmatch(p)[1])
+	case req.Method == http.MethodPost && statusesPath.MatchString(p):
+		code = http.StatusCreated
+		fmt.Fprintf(t.Out, "[dry-run] Would set status %q on %s to %s: %s", field("context"), statusesPath.FindStringSubmatch(p)[1], field("state"), field("description"))
+		if url := field("target_url"); url != "" {
+			fmt.Fprintf(t.Out, " (%s)", url)
+		}
+		fmt.Fprintln(t.Out)
+	case req.Method == http.MethodPost && checkRunsPath.MatchString(p):
+		code = http.StatusCreated
+		fields["id"] = 1
+		fmt.Fprintf(t.Out, "[dry-run] Would create check run %q: %s%s\n", field("name"), field("status"), checkRunDetails(fields))
+	case req.Method == http.MethodPatch && checkRunPath.MatchString(p):
+		fmt.Fprintf(t.Out, "[dry-run] Would update check run %s: %s%s\n", checkRunPath.FindStringSubmatch(p)[1], field("status"), checkRunDetails(fields))
+	case req.Method == http.MethodPost && reviewsPath.MatchString(p):
+		var review github.PullRequestReviewRequest
+		json.Unmarshal(body, &review)
+		fmt.Fprintf(t.Out, "[dry-run] Would post a review on #%s with %d comments\n", reviewsPath.FindStringSubmatch(p)[1], len(review.Comments))
+		for _, c := range review.Comments {
+			fmt.Fprintf(t.Out, "[dry-run] Review comment on %s:%d:\n%s\n", c.GetPath(), c.GetLine(), c.GetBody())
+		}
+	default:
+		fmt.Fprintf(t.Out, "[dry-run] Would send %s %s\n%s\n", req.Method, p, body)
+	}
+	if code == http.StatusNoContent {
+		return jsonResponse(req, code, nil)
+	}
+	return jsonResponse(req, code, fields)
+}
+
+// graphql sends GraphQL queries and prints the mutations, answering the ones ghpc sends as successful
+func (t *DryRunTransport) graphql(req *http.Request, body []byte) (*http.Response, error) {
+	var request struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("error decoding GraphQL request: %w", err)
+	}
+	query := strings.TrimSpace(request.Query)
+	if !strings.HasPrefix(query, "mutation") {
+		if t.Offline {
+			return jsonResponse(req, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{}}), nil
+		}
+		return t.Base.RoundTrip(req)
+	}
+	id := fmt.Sprint(request.Variables["id"])
+	var data map[string]interface{}
+	switch {
+	case strings.Contains(query, "minimizeComment"):
+		fmt.Fprintf(t.Out, "[dry-run] Would minimize comment %s as outdated\n", id)
+		data = map[string]interface{}{"minimizeComment": map[string]interface{}{
+			"minimizedComment": map[string]interface{}{"isMinimized": true, "minimizedReason": "outdated"},
+		}}
+	case strings.Contains(query, "updateIssueComment"):
+		fmt.Fprintf(t.Out, "[dry-run] Would update comment %s:\n%s\n[dry-run] End of comment\n", id, request.Variables["body"])
+		data = map[string]interface{}{"updateIssueComment": map[string]interface{}{
+			"issueComment": map[string]interface{}{"body": request.Variables["body"]},
+		}}
+	case strings.Contains(query, "resolveReviewThread"):
+		fmt.Fprintf(t.Out, "[dry-run] Would resolve review thread %s\n", id)
+		data = map[string]interface{}{"resolveReviewThread": map[string]interface{}{
+			"thread": map[string]interface{}{"id": id, "isResolved": true},
+		}}
+	default:
+		fmt.Fprintf(t.Out, "[dry-run] Would send GraphQL mutation:\n%s\n", query)
+		data = map[string]interface{}{}
+	}
+	return jsonResponse(req, http.StatusOK, map[string]interface{}{"data": data}), nil
+}
+
+// checkRunDetails describes a check run's conclusion and annotations
+func checkRunDetails(fields map[string]interface{}) string {
+	details := ""
+	if conclusion, ok := fields["conclusion"].(string); ok {
+		details += " (" + conclusion + ")"
+	}
+	if output, ok := fields["output"].(map[string]interface{}); ok {
+		if annotations, ok := output["annotations"].([]interface{}); ok {
+			details += fmt.Sprintf(", %d annotations", len(annotations))
+		}
+	}
+	return details
+}
+
+// offlineResponse answers a read as if the PR had no comments, statuses or changed files
+func offlineResponse(req *http.Request) *http.Response {
+	last := path.Base(req.URL.Path)
+	if last == "status" {
+		return jsonResponse(req, http.StatusOK, map[string]interface{}{"state": "pending", "statuses": []interface{}{}, "total_count": 0})
+	}
+	for _, suffix := range emptyListSuffixes {
+		if last == suffix {
+			return jsonResponse(req, http.StatusOK, []interface{}{})
+		}
+	}
+	return jsonResponse(req, http.StatusNotFound, map[string]interface{}{"message": "Not available in a dry run without GITHUB_TOKEN"})
+}
+
+func jsonResponse(req *http.Request, code int, v interface{}) *http.Response {
+	body := []byte{}
+	if v != nil {
+		body, _ = json.Marshal(v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gh-pr-commenter/pkg/diff"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+)
+
+// ListChangedFilesWithRetry lists the files changed by a pull request with retry logic and pagination
+func ListChangedFilesWithRetry(ctx context.Context, client *github.Client, owner, repo string, pullNum int) (diff.Changes, error) {
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		changes := diff.Changes{}
+		opts := &github.ListOptions{PerPage: 100}
+		for {
+			var files []*github.CommitFile
+			var resp *github.Response
+			files, resp, err = client.PullRequests.ListFiles(ctx, owner, repo, pullNum, opts)
+			if err != nil {
+				fmt.Printf("Error listing changed files (attempt %d/%d): %v\n", i+1, maxRetries, err)
+				time.Sleep(time.Second * time.Duration(1<<i)) // Exponential backoff
+				break
+			}
+			for _, file := range files {
+				changes.Add(diff.ParsePatch(file.GetFilename(), file.GetPatch()))
+			}
+			if resp.NextPage == 0 {
+				return changes, nil
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	return nil, fmt.Errorf("error listing changed files after %d retries: %w", maxRetries, err)
+}
+
+// ListOpenPullRequests lists the open pull requests whose head is sha or, when sha has none, branch.
+// PRs that merely contain sha, e.g. those stacked on the PR it belongs to, aren't included.
+func ListOpenPullRequests(ctx context.Context, client *github.Client, owner, repo, sha, branch string) ([]*github.PullRequest, error) {
+	var open []*github.PullRequest
+	if sha != "" {
+		opts := &github.PullRequestListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			pulls, resp, err := client.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, opts)
+			if err != nil {
+				return nil, fmt.Errorf("error listing pull requests for commit %s: %w", sha, err)
+			}
+			for _, pull := range pulls {
+				if pull.GetState() == "open" && pull.GetHead().GetSHA() == sha {
+					open = append(open, pull)
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	if len(open) > 0 || branch == "" {
+		return open, nil
+	}
+	// Branches of forks can't be looked up, as their owner isn't known
+	opts := &github.PullRequestListOptions{State: "open", Head: owner + ":" + branch, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		pulls, resp, err := client.PullRequests.List(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing pull requests for branch %s: %w", branch, err)
+		}
+		open = append(open, pulls...)
+		if resp.NextPage == 0 {
+			return open, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// CreateReview posts a pull request review with line comments
+func CreateReview(ctx context.Context, client *github.Client, owner, repo string, pullNum int, review *github.PullRequestReviewRequest) error {
+	_, _, err := client.PullRequests.CreateReview(ctx, owner, repo, pullNum, review)
+	if err != nil {
+		return fmt.Errorf("error creating review: %w", err)
+	}
+	fmt.Printf("Review posted with %d comments.\n", len(review.Comments))
+	return nil
+}
+
+// ResolveReviewThreads resolves the unresolved review threads whose first comment contains marker
+func ResolveReviewThreads(ctx context.Context, graphqlClient *graphql.Client, owner, repo string, pullNum int, marker string) error {
+	var threadIDs []string
+	var cursor *string
+	for {
+		req := graphql.NewRequest(`
+			query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
+				repository(owner: $owner, name: $repo) {
+					pullRequest(number: $number) {
+						reviewThreads(first: 100, after: $cursor) {
+							pageInfo {
+								hasNextPage
+								endCursor
+							}
+							nodes {
+								id
+								isResolved
+								comments(first: 1) {
+									nodes {
+										body
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		`)
+		req.Var("owner", owner)
+		req.Var("repo", repo)
+		req.Var("number", pullNum)
+		req.Var("cursor", cursor)
+		req.Header.Set("Authorization", "Bearer "+os.Getenv("GITHUB_TOKEN"))
+
+		var respData struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						PageInfo struct {
+							HasNextPage bool
+							EndCursor   string
+						}
+						Nodes []struct {
+							ID         string
+							IsResolved bool
+							Comments   struct {
+								Nodes []struct {
+									Body string
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		if err := graphqlClient.Run(ctx, req, &respData); err != nil {
+			return fmt.Errorf("error listing review threads: %w", err)
+		}
+
+		threads := respData.Repository.PullRequest.ReviewThreads
+		for _, thread := range threads.Nodes {
+			if thread.IsResolved || len(thread.Comments.Nodes) == 0 || !containsMarker(thread.Comments.Nodes[0].Body, marker) {
+				continue
+			}
+			threadIDs = append(threadIDs, thread.ID)
+		}
+		if !threads.PageInfo.HasNextPage {
+			break
+		}
+		cursor = &threads.PageInfo.EndCursor
+	}
+
+	// Resolved once all pages are listed, so resolving doesn't change the pages still to come
+	for _, id := range threadIDs {
+		if err := resolveReviewThread(ctx, graphqlClient, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containsMarker reports whether body carries marker
+func containsMarker(body, marker string) bool {
+	return marker != "" && strings.Contains(body, marker)
+}
+
+// resolveReviewThread sends the resolveReviewThread GraphQL mutation
+func resolveReviewThread(ctx context.Context, graphqlClient *graphql.Client, threadID string) error {
+	req := graphql.NewRequest(`
+		mutation($id: ID!) {
+			resolveReviewThread(input: {threadId: $id}) {
+				thread {
+					isResolved
+				}
+			}
+		}
+	`)
+	req.Var("id", threadID)
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("GITHUB_TOKEN"))
+
+	var respData struct {
+		ResolveReviewThread struct {
+			Thread struct {
+				IsResolved bool
+			}
+		}
+	}
+	if err := graphqlClient.Run(ctx, req, &respData); err != nil {
+		return fmt.Errorf("error resolving review thread: %w", err)
+	}
+	fmt.Printf("Review thread resolved: %s\n", threadID)
+	return nil
+}
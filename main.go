@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
+	"github.com/google/go-github/v41/github"
 	"github.com/machinebox/graphql"
 	"gh-pr-commenter/cmd"
 	"gh-pr-commenter/config"
 	"gh-pr-commenter/internal"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -47,6 +51,129 @@ var commentCmd = &cobra.Command{
 	},
 }
 
+var reportCmd = &cobra.Command{
+	Use:   "report [kind] [file...]",
+	Short: "Post a tool's report file as a PR comment",
+	Long: `Parses report files of the given kind (tflint, trivy, sarif, junit) and posts the findings as a comment on the
+specified pull request, setting the commit status from the --fail-on policy. Files may be glob patterns.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		executeCommand("report", args)
+	},
+}
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline [kind] [file...]",
+	Short: "Record a tool's current findings as known",
+	Long: `Parses report files of the given kind (tflint, trivy, sarif) and records their findings in the baseline file.
+Commit the file; exec and report with --baseline then only report and fail on findings not recorded in it.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(c *cobra.Command, args []string) {
+		if err := cmd.Baseline(args[0], args[1:]); err != nil {
+			config.GetLogger().Fatal("Error recording baseline", zap.Error(err))
+		}
+	},
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render [command]",
+	Short: "Preview the comments for captured output",
+	Long: `Renders a captured output file with a template exactly as the comment command would, split into the same parts,
+and prints the result as markdown or as an HTML page for a browser. The command defaults to the one the output file
+was captured for. HTML is rendered by GitHub's Markdown API, so it needs network access; GITHUB_TOKEN is used when set,
+for a higher rate limit. Markdown is rendered offline.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		templateFile, _ := c.Flags().GetString("template")
+		input, _ := c.Flags().GetString("input")
+		format, _ := c.Flags().GetString("format")
+		command := strings.Join(args, " ")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		client := github.NewClient(nil)
+		if os.Getenv("GITHUB_TOKEN") != "" {
+			client = internal.NewGitHubClient(ctx)
+		}
+		if err := cmd.Render(ctx, client, command, input, templateFile, format, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rendering comment: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Set or read commit statuses on HEAD_COMMIT",
+	Long:  `Sets or reads commit statuses on HEAD_COMMIT. PULL_NUM isn't required, so statuses can be managed outside PRs.`,
+}
+
+var statusSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Post a commit status",
+	Long:  `Posts a commit status on HEAD_COMMIT, e.g. to mark a step pending before it runs or skipped when it doesn't.`,
+	Args:  cobra.NoArgs,
+	Run: func(c *cobra.Command, args []string) {
+		state, _ := c.Flags().GetString("state")
+		statusContext, _ := c.Flags().GetString("context")
+		description, _ := c.Flags().GetString("description")
+		targetURL, _ := c.Flags().GetString("url")
+		executeStatus(func(ctx context.Context, client *github.Client, cnf *config.Config) error {
+			if targetURL == "" {
+				targetURL = cnf.StatusTargetURL
+			}
+			return cmd.SetStatus(ctx, client, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.HeadCommit, state, statusContext, description, targetURL)
+		})
+	},
+}
+
+var statusGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Print the combined commit status",
+	Long: `Prints the combined status of HEAD_COMMIT and the latest status of each context.
+With --context, prints only that context's state, or "missing", so workflows can gate on other checks.`,
+	Args: cobra.NoArgs,
+	Run: func(c *cobra.Command, args []string) {
+		statusContext, _ := c.Flags().GetString("context")
+		executeStatus(func(ctx context.Context, client *github.Client, cnf *config.Config) error {
+			return cmd.GetStatus(ctx, client, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.HeadCommit, statusContext, os.Stdout)
+		})
+	},
+}
+
+var statusReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Resolve stale pending ghpc statuses",
+	Long: `Sets the pending statuses ghpc left on HEAD_COMMIT to error, e.g. when a run was killed before it could post
+a final status. Run it as a last step that always runs, so stale statuses don't block merges.`,
+	Args: cobra.NoArgs,
+	Run: func(c *cobra.Command, args []string) {
+		prefix, _ := c.Flags().GetString("prefix")
+		olderThan, _ := c.Flags().GetDuration("older-than")
+		executeStatus(func(ctx context.Context, client *github.Client, cnf *config.Config) error {
+			if prefix == "" {
+				prefix = cnf.StatusContextPrefix
+			}
+			reconciled, err := cmd.ReconcileStatus(ctx, client, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.HeadCommit, prefix, olderThan, cnf.StatusTargetURL)
+			for _, statusContext := range reconciled {
+				fmt.Printf("Resolved stale status: %s\n", statusContext)
+			}
+			return err
+		})
+	},
+}
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print the settings detected from the CI environment",
+	Long: `Prints the CI system ghpc detected (Atlantis, GitHub Actions, Buildkite or Jenkins) and the settings it reads,
+with where each one comes from. Variables set explicitly take precedence over the detected ones.`,
+	Args: cobra.NoArgs,
+	Run: func(c *cobra.Command, args []string) {
+		cmd.Env(os.Stdout)
+	},
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of ghpc",
@@ -57,8 +184,60 @@ var versionCmd = &cobra.Command{
 }
 
 func main() {
+	rootCmd.PersistentFlags().String("profile", "", "Output profile to render the command's output with (default: picked by command name)")
+	viper.BindPFlag("PROFILE", rootCmd.PersistentFlags().Lookup("profile"))
+	rootCmd.PersistentFlags().String("fail-on", "", "Comma-separated severities that fail the status, e.g. CRITICAL,HIGH (default: any finding)")
+	viper.BindPFlag("FAIL_ON", rootCmd.PersistentFlags().Lookup("fail-on"))
+	rootCmd.PersistentFlags().Bool("review", false, "Post findings on changed lines as inline PR review comments")
+	viper.BindPFlag("REVIEW_COMMENTS", rootCmd.PersistentFlags().Lookup("review"))
+	rootCmd.PersistentFlags().String("only-changed", "", "Only report findings in files (files) or on lines (lines) the PR changed")
+	viper.BindPFlag("ONLY_CHANGED", rootCmd.PersistentFlags().Lookup("only-changed"))
+	rootCmd.PersistentFlags().String("baseline", "", "Baseline file of known findings, which aren't reported or failed on (baseline command default: "+config.DefaultBaselineFile+")")
+	viper.BindPFlag("BASELINE_FILE", rootCmd.PersistentFlags().Lookup("baseline"))
+	rootCmd.PersistentFlags().String("result-store", "", "Where findings are stored per commit, a directory or store URL (default: $TMP_GHPC_DIR/results)")
+	viper.BindPFlag("RESULT_STORE", rootCmd.PersistentFlags().Lookup("result-store"))
+	rootCmd.PersistentFlags().Bool("compare-base", false, "Only report findings that are new since the PR's base commit")
+	viper.BindPFlag("COMPARE_BASE", rootCmd.PersistentFlags().Lookup("compare-base"))
+	rootCmd.PersistentFlags().String("pull-match", "", "Which PR to use when several open ones contain HEAD_COMMIT: newest, oldest or error (default: newest)")
+	viper.BindPFlag("PULL_MATCH", rootCmd.PersistentFlags().Lookup("pull-match"))
+	rootCmd.PersistentFlags().Bool("status-only", false, "Only post commit statuses, no PR comments or reviews; used when no PR is found")
+	viper.BindPFlag("STATUS_ONLY", rootCmd.PersistentFlags().Lookup("status-only"))
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Print the comments, statuses and other writes to GitHub instead of sending them; GITHUB_TOKEN is optional")
+	viper.BindPFlag("DRY_RUN", rootCmd.PersistentFlags().Lookup("dry-run"))
+	execCmd.Flags().Duration("timeout", 0, "Kill the command if it runs longer than this duration (e.g. 30m)")
+	viper.BindPFlag("COMMAND_TIMEOUT", execCmd.Flags().Lookup("timeout"))
+	execCmd.Flags().Bool("timestamps", false, "Prefix each line streamed to the console with a timestamp")
+	viper.BindPFlag("OUTPUT_TIMESTAMPS", execCmd.Flags().Lookup("timestamps"))
+	execCmd.Flags().Bool("color-diff", false, "Turn green and red output lines into diff + and - lines")
+	viper.BindPFlag("COLOR_TO_DIFF", execCmd.Flags().Lookup("color-diff"))
+	reportCmd.Flags().Bool("annotations", false, "Also post findings with a file and line as check run annotations")
+	viper.BindPFlag("ANNOTATIONS", reportCmd.Flags().Lookup("annotations"))
+	renderCmd.Flags().String("template", "", "Template file (default: TEMPLATE_FILENAME, or the default template for the command)")
+	renderCmd.Flags().String("input", "", "Captured output file, e.g. /tmp/ghpc/.output-tflint.md")
+	renderCmd.Flags().String("format", cmd.RenderMarkdown, "Output format: markdown or html")
+	renderCmd.MarkFlagRequired("input")
+	statusSetCmd.Flags().String("state", "", "Commit state: error, failure, pending or success")
+	statusSetCmd.Flags().String("context", "", "Status context, e.g. ghpc/tflint")
+	statusSetCmd.Flags().String("description", "", "Status description (default: derived from the state)")
+	statusSetCmd.Flags().String("url", "", "Status target URL (default: STATUS_TARGET_URL or the CI run)")
+	statusSetCmd.MarkFlagRequired("state")
+	statusSetCmd.MarkFlagRequired("context")
+	statusGetCmd.Flags().String("context", "", "Only print the state of this status context")
+	statusReconcileCmd.Flags().String("prefix", "", "Only resolve contexts starting with this prefix (default: the ghpc contexts, e.g. ghpc/)")
+	statusReconcileCmd.Flags().Duration("older-than", config.DefaultReconcileOlderThan, "Only resolve statuses pending for longer than this; set it above the longest run, or to 0 to resolve all")
+	commentCmd.Flags().Bool("keep-output", false, "Keep the captured output and rendered comment parts in the ghpc temp dir")
+	viper.BindPFlag("KEEP_OUTPUT", commentCmd.Flags().Lookup("keep-output"))
+
 	rootCmd.AddCommand(execCmd)
 	rootCmd.AddCommand(commentCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(baselineCmd)
+	rootCmd.AddCommand(renderCmd)
+	statusCmd.AddCommand(statusSetCmd)
+	statusCmd.AddCommand(statusGetCmd)
+	statusCmd.AddCommand(statusReconcileCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(envCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -80,12 +259,17 @@ func executeCommand(runCommand string, args []string) {
 		config.GetLogger().Warn("Empty command")
 		return
 	}
-	config.Init(cmdName)
+	// PULL_NUM can be looked up once the client exists
+	config.InitStatus(cmdName)
 	cnf := config.GetConfig()
 
-	ctx := context.Background()
-	client := internal.NewGitHubClient(ctx)
-	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+	// SIGINT/SIGTERM cancel the context, which terminates the running command's process group
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, graphqlClient := newClients(ctx, cnf)
+	if err := cmd.ResolvePullRequest(ctx, client, cnf); err != nil {
+		config.GetLogger().Fatal("Error resolving the pull request", zap.Error(err))
+	}
 
 	var err error
 	switch runCommand {
@@ -93,6 +277,8 @@ func executeCommand(runCommand string, args []string) {
 		err = cmd.ExecuteAndComment(ctx, client, graphqlClient, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.PullNum, command)
 	case "comment":
 		err = cmd.Comment(ctx, client, graphqlClient, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.PullNum, command)
+	case "report":
+		err = cmd.Report(ctx, client, graphqlClient, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.PullNum, args[0], args[1:])
 	default:
 		config.GetLogger().Fatal("unknown command", zap.String("command", runCommand))
 	}
@@ -100,3 +286,24 @@ func executeCommand(runCommand string, args []string) {
 		config.GetLogger().Fatal("Error executing command", zap.Error(err))
 	}
 }
+
+// executeStatus runs a status subcommand, which only needs the settings to post commit statuses
+func executeStatus(run func(ctx context.Context, client *github.Client, cnf *config.Config) error) {
+	config.InitStatus("status")
+	cnf := config.GetConfig()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, _ := newClients(ctx, cnf)
+	if err := run(ctx, client, cnf); err != nil {
+		config.GetLogger().Fatal("Error executing status command", zap.Error(err))
+	}
+}
+
+// newClients returns the GitHub REST and GraphQL clients, which only print writes in a dry run
+func newClients(ctx context.Context, cnf *config.Config) (*github.Client, *graphql.Client) {
+	if cnf.DryRun {
+		return internal.NewDryRunGitHubClients(ctx, os.Stdout)
+	}
+	return internal.NewGitHubClient(ctx), graphql.NewClient("https://api.github.com/graphql")
+}
@@ -3,11 +3,14 @@ package comments
 import (
 	"context"
 	"fmt"
-	"strings"
 	"os"
+	"strings"
+	"unicode/utf8"
 
-	"gh-pr-commenter/internal"
 	"gh-pr-commenter/config"
+	"gh-pr-commenter/internal"
+	"gh-pr-commenter/pkg/output"
+	"gh-pr-commenter/pkg/status"
 
 	"github.com/google/go-github/v41/github"
 	"github.com/machinebox/graphql"
@@ -16,6 +19,12 @@ import (
 
 const maxCommentLength = 55000
 
+// maxBodyLength is GitHub's limit on the size of a comment body
+const maxBodyLength = 65536
+
+// truncatedStreamNote ends a stream cut short to fit a comment
+const truncatedStreamNote = "\n... (truncated)\n"
+
 // Comment posts comments on the specified PR
 func Comment(ctx context.Context, client *github.Client, graphqlClient *graphql.Client, owner, repo string, prNumber string, command string) error {
 	logger := config.GetLogger()
@@ -26,16 +35,18 @@ func Comment(ctx context.Context, client *github.Client, graphqlClient *graphql.
 	cmdName := cmdArgs[0]
 	config.Init(cmdName)
 	cnf := config.GetConfig()
-	outputFilename := fmt.Sprintf("%s/.output-%s.md", cnf.TmpGhpcDir ,cmdName)
-	output, err := os.ReadFile(outputFilename)
+	outputFilename := output.FileName(cnf.TmpGhpcDir, cmdName)
+	combined, err := os.ReadFile(outputFilename)
 	if err != nil {
 		return fmt.Errorf("error reading output file: %w", err)
 	}
-	logger.Info("Output file read successfully", zap.String("output", string(output)))
-
-	parts := SplitMessage(string(output))
+	logger.Info("Output file read successfully", zap.String("output", string(combined)))
+	stdout, stderr, err := ReadStreams(outputFilename)
+	if err != nil {
+		return err
+	}
 
-	err = CreateDefaultTemplate(cnf.TemplateFilename, command)
+	err = CreateDefaultTemplate(cnf.TemplateFilename, TemplateCommand(command, cnf.Profile))
 	if err != nil {
 		return fmt.Errorf("error creating default template: %w", err)
 	}
@@ -45,23 +56,183 @@ func Comment(ctx context.Context, client *github.Client, graphqlClient *graphql.
 		return fmt.Errorf("error reading template file: %w", err)
 	}
 
-	for i, part := range parts {
-		partWithID := strings.Replace(string(templateContent), "---OUTPUT---", part, 1)
-		partWithID = fmt.Sprintf("## %s output\n%s <!-- Part #%d -->", cmdName, partWithID, i+1)
-		newFilename := fmt.Sprintf(".comment-%s-%s-%s-part-%d.md", repo, prNumber, cmdName, i+1)
-		err := os.WriteFile(newFilename, []byte(partWithID), 0644)
-		if err != nil {
-			return fmt.Errorf("error writing to file: %w", err)
+	parts, err := RenderParts(string(templateContent), command, string(combined), stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	if cnf.StatusOnly {
+		logger.Info("No pull request to comment on, the rendered comment isn't posted")
+	}
+	var firstComment *github.IssueComment
+	for i, partWithID := range parts {
+		if cnf.KeepOutput {
+			// Keep the rendered parts next to the captured output for debugging
+			newFilename := fmt.Sprintf("%s/.comment-%s-%s-%s-part-%d.md", cnf.TmpGhpcDir, repo, prNumber, cmdName, i+1)
+			err := os.WriteFile(newFilename, []byte(partWithID), 0644)
+			if err != nil {
+				return fmt.Errorf("error writing to file: %w", err)
+			}
 		}
 
-		err = internal.UpsertComment(ctx, client, graphqlClient, owner, repo, prNumber, newFilename, fmt.Sprintf("## %s output", cmdName), fmt.Sprintf("Part #%d", i+1))
+		if cnf.StatusOnly {
+			continue
+		}
+		comment, err := internal.UpsertCommentBody(ctx, client, graphqlClient, owner, repo, prNumber, partWithID, fmt.Sprintf("## %s output", cmdName), fmt.Sprintf("Part #%d", i+1))
 		if err != nil {
 			return fmt.Errorf("error upserting comment: %w", err)
 		}
+		if firstComment == nil {
+			firstComment = comment
+		}
+	}
+
+	// Point the status exec posted at the comment; the status is still valid without the link
+	if firstComment.GetHTMLURL() != "" {
+		err = status.LinkCommitStatus(ctx, client, owner, repo, cnf.HeadCommit, cnf.GHStatusContext, firstComment.GetHTMLURL())
+		if err != nil {
+			logger.Warn("Error linking commit status to comment", zap.Error(err))
+		}
+	}
+
+	// A dry run leaves the output in place for the real run, and nothing was posted in status-only mode
+	if cnf.KeepOutput || cnf.DryRun || cnf.StatusOnly {
+		return nil
+	}
+	for _, filename := range []string{
+		outputFilename,
+		output.StreamFileName(cnf.TmpGhpcDir, cmdName, "stdout"),
+		output.StreamFileName(cnf.TmpGhpcDir, cmdName, "stderr"),
+	} {
+		err = ArchiveOutput(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error archiving output file: %w", err)
+		}
 	}
+	logger.Info("Output file archived", zap.String("file", outputFilename))
 	return nil
 }
 
+// ArchiveOutput moves a consumed output file aside so a re-run doesn't re-post stale sections
+func ArchiveOutput(outputFilename string) error {
+	archivedFilename := strings.TrimSuffix(outputFilename, ".md") + ".posted.md"
+	return os.Rename(outputFilename, archivedFilename)
+}
+
+// RenderParts renders the comment parts posted for command's captured output, in order.
+// The full streams in .Combined, .Stdout and .Stderr are cut short when a part would exceed maxBodyLength.
+func RenderParts(templateContent, command, combined, stdout, stderr string) ([]string, error) {
+	cmdName := strings.Fields(command)[0]
+	parts := SplitMessage(combined)
+	rendered := make([]string, 0, len(parts))
+	for i, part := range parts {
+		data := TemplateData{
+			Command:  command,
+			Output:   part,
+			Combined: combined,
+			Stdout:   stdout,
+			Stderr:   stderr,
+			Part:     i + 1,
+			Parts:    len(parts),
+		}
+		body, err := renderPart(templateContent, cmdName, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > maxBodyLength {
+			body, err = renderTruncatedPart(templateContent, cmdName, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rendered = append(rendered, body)
+	}
+	return rendered, nil
+}
+
+// renderPart renders one comment part with its heading and part marker
+func renderPart(templateContent, cmdName string, data TemplateData) (string, error) {
+	partWithID, err := RenderTemplate(templateContent, data)
+	if err != nil {
+		return "", fmt.Errorf("error rendering template: %w", err)
+	}
+	return fmt.Sprintf("## %s output\n%s <!-- Part #%d -->", cmdName, partWithID, data.Part), nil
+}
+
+// renderTruncatedPart renders a part whose streams are too long for a comment,
+// searching for the longest stream length that still fits within maxBodyLength
+func renderTruncatedPart(templateContent, cmdName string, full TemplateData) (string, error) {
+	render := func(limit int) (string, error) {
+		data := full
+		data.Combined = truncateStream(full.Combined, limit)
+		data.Stdout = truncateStream(full.Stdout, limit)
+		data.Stderr = truncateStream(full.Stderr, limit)
+		return renderPart(templateContent, cmdName, data)
+	}
+	body, err := render(0)
+	if err != nil {
+		return "", err
+	}
+	low, high := 0, max(len(full.Combined), len(full.Stdout), len(full.Stderr))
+	for low < high {
+		limit := (low + high + 1) / 2
+		rendered, err := render(limit)
+		if err != nil {
+			return "", err
+		}
+		if len(rendered) <= maxBodyLength {
+			low, body = limit, rendered
+		} else {
+			high = limit - 1
+		}
+	}
+	return body, nil
+}
+
+// truncateStream cuts a captured stream to at most limit bytes, at a line break where there is one, noting the cut
+func truncateStream(stream string, limit int) string {
+	if len(stream) <= limit {
+		return stream
+	}
+	cut := limit - len(truncatedStreamNote)
+	if cut <= 0 {
+		return ""
+	}
+	if lastNewline := strings.LastIndex(stream[:cut], "\n"); lastNewline != -1 {
+		cut = lastNewline + 1
+	}
+	for cut > 0 && !utf8.RuneStart(stream[cut]) {
+		cut--
+	}
+	return stream[:cut] + truncatedStreamNote
+}
+
+// ReadStreams reads the stdout and stderr captured next to outputFilename, which are empty when absent
+func ReadStreams(outputFilename string) (stdout, stderr string, err error) {
+	base := strings.TrimSuffix(outputFilename, ".md")
+	stdout, err = readStreamFile(base + ".stdout.md")
+	if err != nil {
+		return "", "", err
+	}
+	stderr, err = readStreamFile(base + ".stderr.md")
+	if err != nil {
+		return "", "", err
+	}
+	return stdout, stderr, nil
+}
+
+// readStreamFile reads a captured stdout or stderr file, which is absent for output captured by older versions
+func readStreamFile(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading stream file: %w", err)
+	}
+	return string(content), nil
+}
+
 func SplitMessage(message string) []string {
 	var parts []string
 	start := 0
@@ -82,17 +253,61 @@ func SplitMessage(message string) []string {
 	return parts
 }
 
-func CreateDefaultTemplate(filename string, command string) error {
-    content := `
+const diffTemplate = `
 <details><summary>Show Output</summary>
 
-`+"```"+`diff
+` + "```" + `diff
 ---OUTPUT---
-`+"```"+`
+` + "```" + `
 </details>
 `
-	if strings.Contains(command, "trivy") || strings.Contains(command, "tflint") {
-		content = `---OUTPUT---`
+
+// CreateDefaultTemplate writes the default template for command to filename.
+// A custom template already at filename is left untouched.
+func CreateDefaultTemplate(filename string, command string) error {
+	existing, err := os.ReadFile(filename)
+	if err == nil && !isDefaultTemplate(string(existing)) {
+		return nil
+	}
+	return os.WriteFile(filename, []byte(DefaultTemplate(command)), 0644)
+}
+
+// ReadTemplate returns the template Comment renders command's output with:
+// the custom template at filename, or the default template for command when there's none
+func ReadTemplate(filename, command string) (string, error) {
+	existing, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("error reading template file: %w", err)
 	}
-    return os.WriteFile(filename, []byte(content), 0644)
+	if err == nil && !isDefaultTemplate(string(existing)) {
+		return string(existing), nil
+	}
+	return DefaultTemplate(command), nil
+}
+
+// TemplateCommand returns what picks the default template for command's output.
+// Output rendered by a profile is markdown and must not be fenced by the default template.
+func TemplateCommand(command, profile string) string {
+	if profile != "" {
+		return profile
+	}
+	return command
+}
+
+// markdownProfiles are the profiles rendering their output as markdown, matching the ones cmd selects by name
+var markdownProfiles = map[string]bool{"terraform": true, "tflint": true, "trivy": true}
+
+// DefaultTemplate returns the template ghpc generates for command, which is a profile name or a command line.
+// Like the profiles, it's picked by the exact command name, so e.g. `checkov -d terraform/` is fenced as a diff.
+func DefaultTemplate(command string) string {
+	if fields := strings.Fields(command); len(fields) > 0 && markdownProfiles[fields[0]] {
+		return outputPlaceholder
+	}
+	return diffTemplate
+}
+
+// isDefaultTemplate reports whether content is one of the templates ghpc generates
+func isDefaultTemplate(content string) bool {
+	content = strings.TrimSpace(content)
+	return content == strings.TrimSpace(diffTemplate) || content == outputPlaceholder
 }
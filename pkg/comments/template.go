@@ -0,0 +1,52 @@
+package comments
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const outputPlaceholder = "---OUTPUT---"
+
+// templateFieldPattern matches an action referencing a TemplateData field, which makes a template a Go template.
+// Other {{ }}, e.g. a GitHub Actions expression quoted in a template, are left as they are.
+var templateFieldPattern = regexp.MustCompile(`\{\{[^}]*\.(Command|Output|Combined|Stdout|Stderr|Part|Parts)\b`)
+
+// TemplateData is the data available to comment templates
+type TemplateData struct {
+	// Command is the command line the output was captured for
+	Command string
+	// Output is the part of the combined output rendered in this comment
+	Output string
+	// Combined is the full interleaved stdout and stderr output
+	Combined string
+	// Stdout is the full standard output
+	Stdout string
+	// Stderr is the full standard error output
+	Stderr string
+	// Part is the 1-based index of this comment part
+	Part int
+	// Parts is the total number of comment parts
+	Parts int
+}
+
+// RenderTemplate renders a comment template for one part of the output.
+// The ---OUTPUT--- placeholder is replaced with data.Output; templates that reference
+// a field in a Go template action (e.g. {{ .Stderr }}) are executed with data first.
+func RenderTemplate(templateContent string, data TemplateData) (string, error) {
+	rendered := templateContent
+	if templateFieldPattern.MatchString(templateContent) {
+		tmpl, err := template.New("comment").Parse(templateContent)
+		if err != nil {
+			return "", fmt.Errorf("error parsing template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("error executing template: %w", err)
+		}
+		rendered = buf.String()
+	}
+	return strings.Replace(rendered, outputPlaceholder, data.Output, 1), nil
+}
@@ -0,0 +1,91 @@
+package diff
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern matches a unified diff hunk header and captures the new file's start line
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// File is a file changed by a pull request
+type File struct {
+	Path string
+	// Added are the new file's lines the pull request added or modified
+	Added map[int]bool
+	// Commentable are the new file's lines shown in the diff, which review comments can be placed on
+	Commentable map[int]bool
+}
+
+// Changes are the files changed by a pull request, keyed by path
+type Changes map[string]*File
+
+// ParsePatch parses the unified diff patch of one file
+func ParsePatch(path, patch string) *File {
+	file := &File{Path: path, Added: map[int]bool{}, Commentable: map[int]bool{}}
+	line := 0
+	inHunk := false
+	for _, l := range strings.Split(patch, "\n") {
+		if m := hunkHeaderPattern.FindStringSubmatch(l); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			inHunk = true
+			continue
+		}
+		if !inHunk || l == "" {
+			continue
+		}
+		switch l[0] {
+		case '+':
+			file.Added[line] = true
+			file.Commentable[line] = true
+			line++
+		case ' ':
+			file.Commentable[line] = true
+			line++
+		case '-', '\\':
+			// Removed lines and "\ No newline at end of file" don't exist in the new file
+		}
+	}
+	return file
+}
+
+// Add records a changed file
+func (c Changes) Add(file *File) {
+	c[file.Path] = file
+}
+
+// HasFile reports whether the pull request changed path
+func (c Changes) HasFile(path string) bool {
+	_, ok := c[NormalizePath(path)]
+	return ok
+}
+
+// HasAddedLine reports whether the pull request added or modified line in path
+func (c Changes) HasAddedLine(path string, line int) bool {
+	file, ok := c[NormalizePath(path)]
+	return ok && file.Added[line]
+}
+
+// IsCommentable reports whether a review comment can be placed on line in path
+func (c Changes) IsCommentable(path string, line int) bool {
+	file, ok := c[NormalizePath(path)]
+	return ok && file.Commentable[line]
+}
+
+// RepoPath returns the repository-relative path of a path a tool reported relative to the project directory dir.
+// Absolute paths are only normalized.
+func RepoPath(dir, p string) string {
+	p = NormalizePath(p)
+	if dir == "" || strings.HasPrefix(p, "/") {
+		return p
+	}
+	return path.Join(dir, p)
+}
+
+// NormalizePath makes a tool-reported path comparable to the repository-relative paths of a pull request
+func NormalizePath(path string) string {
+	path = strings.TrimPrefix(path, "file://")
+	return strings.TrimPrefix(path, "./")
+}
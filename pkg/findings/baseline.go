@@ -0,0 +1,137 @@
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BaselineVersion is the version of the baseline file format
+const BaselineVersion = 1
+
+var (
+	whitespacePattern = regexp.MustCompile(`\s+`)
+	numberPattern     = regexp.MustCompile(`\d+`)
+)
+
+// Baseline is a record of known findings, keyed by report kind, that don't fail a run
+type Baseline struct {
+	Version int                        `json:"version"`
+	Kinds   map[string][]BaselineEntry `json:"kinds"`
+}
+
+// BaselineEntry is a known finding
+type BaselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Rule        string `json:"rule"`
+	File        string `json:"file"`
+	Message     string `json:"message"`
+}
+
+// BaselineDiff compares a run's findings with a baseline
+type BaselineDiff struct {
+	// New are the findings that aren't in the baseline
+	New []Finding
+	// Fixed counts the baseline entries no longer found
+	Fixed int
+	// Known counts the findings already in the baseline
+	Known int
+}
+
+// Fingerprint identifies a finding by tool, rule, file and normalized message.
+// Line numbers are left out, and numbers in the message are masked, so edits elsewhere in a file don't change it.
+func Fingerprint(f Finding) string {
+	message := strings.ToLower(strings.TrimSpace(f.Message))
+	message = whitespacePattern.ReplaceAllString(message, " ")
+	message = numberPattern.ReplaceAllString(message, "N")
+	sum := sha256.Sum256([]byte(strings.Join([]string{f.Tool, f.Rule, f.File, message}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadBaseline reads a baseline file. A missing file is an empty baseline.
+func LoadBaseline(path string) (*Baseline, error) {
+	baseline := &Baseline{Version: BaselineVersion, Kinds: map[string][]BaselineEntry{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return baseline, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline: %w", err)
+	}
+	if err := json.Unmarshal(data, baseline); err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %w", path, err)
+	}
+	if baseline.Version != BaselineVersion {
+		return nil, fmt.Errorf("error parsing baseline %s: unsupported version %d", path, baseline.Version)
+	}
+	if baseline.Kinds == nil {
+		baseline.Kinds = map[string][]BaselineEntry{}
+	}
+	return baseline, nil
+}
+
+// Save writes the baseline file
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing baseline: %w", err)
+	}
+	return nil
+}
+
+// Record replaces the known findings of kind, sorted so the committed file diffs cleanly
+func (b *Baseline) Record(kind string, findings []Finding) {
+	seen := map[string]bool{}
+	entries := []BaselineEntry{}
+	for _, f := range findings {
+		fingerprint := Fingerprint(f)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		entries = append(entries, BaselineEntry{Fingerprint: fingerprint, Rule: f.Rule, File: f.File, Message: f.Message})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].File != entries[j].File {
+			return entries[i].File < entries[j].File
+		}
+		if entries[i].Rule != entries[j].Rule {
+			return entries[i].Rule < entries[j].Rule
+		}
+		return entries[i].Fingerprint < entries[j].Fingerprint
+	})
+	b.Kinds[kind] = entries
+}
+
+// Compare splits findings into new and known ones against the baseline entries of kind
+func (b *Baseline) Compare(kind string, findings []Finding) BaselineDiff {
+	known := map[string]bool{}
+	for _, entry := range b.Kinds[kind] {
+		known[entry.Fingerprint] = true
+	}
+	var diff BaselineDiff
+	found := map[string]bool{}
+	for _, f := range findings {
+		fingerprint := Fingerprint(f)
+		found[fingerprint] = true
+		if known[fingerprint] {
+			diff.Known++
+			continue
+		}
+		diff.New = append(diff.New, f)
+	}
+	for fingerprint := range known {
+		if !found[fingerprint] {
+			diff.Fixed++
+		}
+	}
+	return diff
+}
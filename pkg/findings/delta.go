@@ -0,0 +1,50 @@
+package findings
+
+import "fmt"
+
+// Delta is the change in findings between a base run and the current one
+type Delta struct {
+	// New are the current findings the base run didn't have
+	New []Finding
+	// Fixed are the base run's findings that are gone
+	Fixed []Finding
+	// Unchanged counts the findings both runs have
+	Unchanged int
+}
+
+// Compare matches current findings against a base run's by fingerprint
+func Compare(base, current []Finding) Delta {
+	inBase := map[string]bool{}
+	for _, f := range base {
+		inBase[Fingerprint(f)] = true
+	}
+	inCurrent := map[string]bool{}
+	var delta Delta
+	for _, f := range current {
+		fingerprint := Fingerprint(f)
+		inCurrent[fingerprint] = true
+		if inBase[fingerprint] {
+			delta.Unchanged++
+			continue
+		}
+		delta.New = append(delta.New, f)
+	}
+	for _, f := range base {
+		if !inCurrent[Fingerprint(f)] {
+			delta.Fixed = append(delta.Fixed, f)
+		}
+	}
+	return delta
+}
+
+// String describes the delta, e.g. "2 new (2 high), 1 fixed (1 medium), 4 unchanged"
+func (d Delta) String() string {
+	return fmt.Sprintf("%s, %s, %d unchanged", countWithSeverities("new", d.New), countWithSeverities("fixed", d.Fixed), d.Unchanged)
+}
+
+func countWithSeverities(label string, findings []Finding) string {
+	if len(findings) == 0 {
+		return "0 " + label
+	}
+	return fmt.Sprintf("%d %s (%s)", len(findings), label, SeverityCounts(findings))
+}
@@ -0,0 +1,120 @@
+package findings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is a finding's severity as reported by its tool, lowercased
+type Severity string
+
+// severityRanks orders the severities used by the supported tools on a common scale
+var severityRanks = map[Severity]int{
+	"critical": 5,
+	"high":     4,
+	"error":    4,
+	"medium":   3,
+	"warning":  3,
+	"low":      2,
+	"notice":   2,
+	"note":     2,
+	"info":     1,
+	"none":     1,
+}
+
+// NewSeverity normalizes a tool's severity label
+func NewSeverity(s string) Severity {
+	return Severity(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// Rank returns the severity's position on the common scale, 0 for unknown severities
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// Finding is a single issue reported by a linter or scanner
+type Finding struct {
+	Tool     string
+	Rule     string
+	Severity Severity
+	File     string
+	Line     int
+	Message  string
+}
+
+// Policy decides which findings fail a run
+type Policy struct {
+	threshold int
+}
+
+// ParsePolicy parses a comma-separated list of severities such as "CRITICAL,HIGH".
+// Findings at or above the lowest listed severity fail the run; an empty list fails on any finding.
+func ParsePolicy(s string) (Policy, error) {
+	policy := Policy{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		severity := NewSeverity(part)
+		rank, ok := severityRanks[severity]
+		if !ok {
+			return Policy{}, fmt.Errorf("unknown severity in fail-on policy: %s", part)
+		}
+		if policy.threshold == 0 || rank < policy.threshold {
+			policy.threshold = rank
+		}
+	}
+	return policy, nil
+}
+
+// Fails reports whether f fails the policy
+func (p Policy) Fails(f Finding) bool {
+	return f.Severity.Rank() >= p.threshold
+}
+
+// Failing returns the findings that fail the policy
+func (p Policy) Failing(findings []Finding) []Finding {
+	var failing []Finding
+	for _, f := range findings {
+		if p.Fails(f) {
+			failing = append(failing, f)
+		}
+	}
+	return failing
+}
+
+// Summary describes findings by count and severity, e.g. "3 issues (1 error, 2 warning)"
+func Summary(findings []Finding) string {
+	if len(findings) == 0 {
+		return "no issues"
+	}
+	noun := "issues"
+	if len(findings) == 1 {
+		noun = "issue"
+	}
+	return fmt.Sprintf("%d %s (%s)", len(findings), noun, SeverityCounts(findings))
+}
+
+// SeverityCounts counts findings per severity, most severe first, e.g. "1 error, 2 warning"
+func SeverityCounts(findings []Finding) string {
+	counts := map[Severity]int{}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	severities := make([]Severity, 0, len(counts))
+	for s := range counts {
+		severities = append(severities, s)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		if severities[i].Rank() != severities[j].Rank() {
+			return severities[i].Rank() > severities[j].Rank()
+		}
+		return severities[i] < severities[j]
+	})
+	parts := make([]string, 0, len(severities))
+	for _, s := range severities {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+	}
+	return strings.Join(parts, ", ")
+}
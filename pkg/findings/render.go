@@ -0,0 +1,56 @@
+package findings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Markdown renders findings as a summary header followed by a table per file
+func Markdown(title string, findings []Finding) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### %s: %s\n\n", title, Summary(findings))
+	if len(findings) == 0 {
+		return sb.String()
+	}
+
+	byFile := map[string][]Finding{}
+	var files []string
+	for _, f := range findings {
+		if _, ok := byFile[f.File]; !ok {
+			files = append(files, f.File)
+		}
+		byFile[f.File] = append(byFile[f.File], f)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		fileFindings := byFile[file]
+		sort.SliceStable(fileFindings, func(i, j int) bool {
+			return fileFindings[i].Line < fileFindings[j].Line
+		})
+		name := file
+		if name == "" {
+			name = "(no file)"
+		}
+		fmt.Fprintf(&sb, "<details open><summary><code>%s</code> (%d)</summary>\n\n", name, len(fileFindings))
+		sb.WriteString("| Line | Severity | Rule | Message |\n")
+		sb.WriteString("| ---: | --- | --- | --- |\n")
+		for _, f := range fileFindings {
+			line := ""
+			if f.Line > 0 {
+				line = fmt.Sprintf("%d", f.Line)
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", line, f.Severity, EscapeCell(f.Rule), EscapeCell(f.Message))
+		}
+		sb.WriteString("\n</details>\n\n")
+	}
+	return sb.String()
+}
+
+// EscapeCell keeps a value on one line inside a markdown table cell
+func EscapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
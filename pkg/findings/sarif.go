@@ -0,0 +1,144 @@
+package findings
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sarifLog is the subset of a SARIF 2.1.0 log ghpc reads
+type sarifLog struct {
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name  string      `json:"name"`
+				Rules []sarifRule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID    string `json:"ruleId"`
+			RuleIndex *int   `json:"ruleIndex"`
+			Level     string `json:"level"`
+			Message   struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine int `json:"startLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+// sarifRule is a rule described by a SARIF tool driver
+type sarifRule struct {
+	ID                   string `json:"id"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// ParseSARIF parses a SARIF 2.1.0 log. Results take the severity from their rule's
+// security-severity score when the tool provides one, and from their level otherwise.
+func ParseSARIF(data []byte) ([]Finding, error) {
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("error parsing SARIF: %w", err)
+	}
+	if !strings.HasPrefix(log.Version, "2.1") {
+		return nil, fmt.Errorf("error parsing SARIF: unsupported version %q", log.Version)
+	}
+
+	findings := []Finding{}
+	for _, run := range log.Runs {
+		rules := map[string]sarifRule{}
+		for _, rule := range run.Tool.Driver.Rules {
+			rules[rule.ID] = rule
+		}
+		for _, result := range run.Results {
+			var rule sarifRule
+			if result.RuleIndex != nil && *result.RuleIndex < len(run.Tool.Driver.Rules) {
+				rule = run.Tool.Driver.Rules[*result.RuleIndex]
+			} else {
+				rule = rules[result.RuleID]
+			}
+			ruleID := result.RuleID
+			if ruleID == "" {
+				ruleID = rule.ID
+			}
+
+			f := Finding{
+				Tool:     run.Tool.Driver.Name,
+				Rule:     ruleID,
+				Severity: sarifSeverity(result.Level, rule),
+				Message:  result.Message.Text,
+			}
+			if len(result.Locations) > 0 {
+				location := result.Locations[0].PhysicalLocation
+				f.File = strings.TrimPrefix(location.ArtifactLocation.URI, "file://")
+				f.Line = location.Region.StartLine
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// sarifSeverity maps a rule's security-severity score onto critical/high/medium/low,
+// falling back to the result's level, the rule's default level and finally warning
+func sarifSeverity(level string, rule sarifRule) Severity {
+	if score, ok := securitySeverity(rule.Properties["security-severity"]); ok {
+		switch {
+		case score >= 9:
+			return "critical"
+		case score >= 7:
+			return "high"
+		case score >= 4:
+			return "medium"
+		case score > 0:
+			return "low"
+		}
+	}
+	if level == "" {
+		level = rule.DefaultConfiguration.Level
+	}
+	if level == "" {
+		level = "warning"
+	}
+	return NewSeverity(level)
+}
+
+// securitySeverity reads a security-severity property, which tools write as a string or a number
+func securitySeverity(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		score, err := strconv.ParseFloat(v, 64)
+		return score, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Tools returns the distinct tool names of findings, in the order they first appear
+func Tools(findings []Finding) []string {
+	seen := map[string]bool{}
+	var tools []string
+	for _, f := range findings {
+		if f.Tool != "" && !seen[f.Tool] {
+			seen[f.Tool] = true
+			tools = append(tools, f.Tool)
+		}
+	}
+	return tools
+}
@@ -0,0 +1,64 @@
+package findings
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// tflintRange is a source range in tflint's JSON output
+type tflintRange struct {
+	Filename string `json:"filename"`
+	Start    struct {
+		Line int `json:"line"`
+	} `json:"start"`
+}
+
+// ParseTflintJSON parses the output of `tflint --format=json`.
+// Errors tflint hit while loading the configuration are reported as error findings.
+func ParseTflintJSON(data []byte) ([]Finding, error) {
+	var report struct {
+		Issues []struct {
+			Rule struct {
+				Name     string `json:"name"`
+				Severity string `json:"severity"`
+			} `json:"rule"`
+			Message string      `json:"message"`
+			Range   tflintRange `json:"range"`
+		} `json:"issues"`
+		Errors []struct {
+			Message  string       `json:"message"`
+			Severity string       `json:"severity"`
+			Range    *tflintRange `json:"range"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("error parsing tflint JSON: %w", err)
+	}
+	if report.Issues == nil && report.Errors == nil {
+		return nil, fmt.Errorf("error parsing tflint JSON: no issues or errors field")
+	}
+
+	findings := []Finding{}
+	for _, issue := range report.Issues {
+		findings = append(findings, Finding{
+			Tool:     "tflint",
+			Rule:     issue.Rule.Name,
+			Severity: NewSeverity(issue.Rule.Severity),
+			File:     issue.Range.Filename,
+			Line:     issue.Range.Start.Line,
+			Message:  issue.Message,
+		})
+	}
+	for _, e := range report.Errors {
+		f := Finding{Tool: "tflint", Severity: NewSeverity(e.Severity), Message: e.Message}
+		if f.Severity == "" {
+			f.Severity = "error"
+		}
+		if e.Range != nil {
+			f.File = e.Range.Filename
+			f.Line = e.Range.Start.Line
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
@@ -0,0 +1,175 @@
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"gh-pr-commenter/pkg/findings"
+)
+
+// Suite is a JUnit test suite
+type Suite struct {
+	Name   string  `xml:"name,attr"`
+	Time   float64 `xml:"time,attr"`
+	Cases  []Case  `xml:"testcase"`
+	Suites []Suite `xml:"testsuite"`
+}
+
+// Case is a single test case
+type Case struct {
+	Name      string   `xml:"name,attr"`
+	Classname string   `xml:"classname,attr"`
+	Time      float64  `xml:"time,attr"`
+	Failure   *Outcome `xml:"failure"`
+	Error     *Outcome `xml:"error"`
+	Skipped   *Outcome `xml:"skipped"`
+}
+
+// Outcome is the failure, error or skip detail of a test case
+type Outcome struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Counts are the number of passed, failed and skipped test cases. Errors count as failures.
+type Counts struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Total returns the number of test cases counted
+func (c Counts) Total() int {
+	return c.Passed + c.Failed + c.Skipped
+}
+
+// Add returns the sum of c and other
+func (c Counts) Add(other Counts) Counts {
+	return Counts{Passed: c.Passed + other.Passed, Failed: c.Failed + other.Failed, Skipped: c.Skipped + other.Skipped}
+}
+
+// String summarizes the counts, e.g. "10 passed, 2 failed, 1 skipped"
+func (c Counts) String() string {
+	return fmt.Sprintf("%d passed, %d failed, %d skipped", c.Passed, c.Failed, c.Skipped)
+}
+
+// Parse parses a JUnit XML report whose root is either <testsuites> or a single <testsuite>.
+// Nested suites are flattened.
+func Parse(data []byte) ([]Suite, error) {
+	var root struct {
+		XMLName xml.Name
+		Suite
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error parsing JUnit XML: %w", err)
+	}
+	switch root.XMLName.Local {
+	case "testsuites":
+		return flatten(root.Suites), nil
+	case "testsuite":
+		return flatten([]Suite{root.Suite}), nil
+	default:
+		return nil, fmt.Errorf("error parsing JUnit XML: unexpected root element <%s>", root.XMLName.Local)
+	}
+}
+
+// flatten lists nested suites alongside their parents
+func flatten(suites []Suite) []Suite {
+	var flat []Suite
+	for _, s := range suites {
+		nested := s.Suites
+		s.Suites = nil
+		if len(s.Cases) > 0 || len(nested) == 0 {
+			flat = append(flat, s)
+		}
+		flat = append(flat, flatten(nested)...)
+	}
+	return flat
+}
+
+// Counts counts the suite's test cases by outcome
+func (s Suite) Counts() Counts {
+	var c Counts
+	for _, tc := range s.Cases {
+		switch {
+		case tc.Failure != nil || tc.Error != nil:
+			c.Failed++
+		case tc.Skipped != nil:
+			c.Skipped++
+		default:
+			c.Passed++
+		}
+	}
+	return c
+}
+
+// Total counts the test cases of all suites by outcome
+func Total(suites []Suite) Counts {
+	var c Counts
+	for _, s := range suites {
+		c = c.Add(s.Counts())
+	}
+	return c
+}
+
+// Markdown renders a summary table per suite followed by each failure in a collapsible block
+func Markdown(suites []Suite) string {
+	total := Total(suites)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### JUnit: %s\n\n", total)
+	if len(suites) == 0 {
+		sb.WriteString("No test suites found.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("| Suite | Passed | Failed | Skipped | Time |\n")
+	sb.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+	for _, s := range suites {
+		c := s.Counts()
+		fmt.Fprintf(&sb, "| %s | %d | %d | %d | %.2fs |\n", findings.EscapeCell(s.Name), c.Passed, c.Failed, c.Skipped, s.Time)
+	}
+	sb.WriteString("\n")
+
+	for _, s := range suites {
+		for _, tc := range s.Cases {
+			outcome := tc.Failure
+			if outcome == nil {
+				outcome = tc.Error
+			}
+			if outcome == nil {
+				continue
+			}
+			name := tc.Name
+			if tc.Classname != "" {
+				name = tc.Classname + "." + tc.Name
+			}
+			fmt.Fprintf(&sb, "<details><summary>%s: <code>%s</code>", escapeHTML(s.Name), escapeHTML(name))
+			if outcome.Message != "" {
+				fmt.Fprintf(&sb, " - %s", escapeHTML(firstLine(outcome.Message)))
+			}
+			sb.WriteString("</summary>\n\n")
+			if text := strings.TrimSpace(outcome.Text); text != "" {
+				fmt.Fprintf(&sb, "```\n%s\n```\n", text)
+			} else if outcome.Message != "" {
+				fmt.Fprintf(&sb, "```\n%s\n```\n", outcome.Message)
+			}
+			sb.WriteString("</details>\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+// escapeHTML escapes text placed inside HTML tags
+func escapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
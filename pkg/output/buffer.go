@@ -0,0 +1,96 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Buffer captures command output in memory up to a limit and spills the rest to a temporary file
+type Buffer struct {
+	limit int
+	dir   string
+	mem   bytes.Buffer
+	file  *os.File
+	size  int64
+}
+
+// NewBuffer returns a Buffer that keeps up to limit bytes in memory before spilling to a file in dir
+func NewBuffer(limit int, dir string) *Buffer {
+	return &Buffer{limit: limit, dir: dir}
+}
+
+// Write appends p to the buffer, moving the captured output to disk once the memory limit is exceeded
+func (b *Buffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.mem.Len()+len(p) > b.limit {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+	var n int
+	var err error
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+// spill moves the in-memory contents to a temporary file that receives all further writes
+func (b *Buffer) spill() error {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return fmt.Errorf("error creating spill directory: %w", err)
+	}
+	file, err := os.CreateTemp(b.dir, ".spill-*")
+	if err != nil {
+		return fmt.Errorf("error creating spill file: %w", err)
+	}
+	if _, err := b.mem.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("error writing spill file: %w", err)
+	}
+	b.file = file
+	return nil
+}
+
+// Len returns the number of bytes captured
+func (b *Buffer) Len() int64 {
+	return b.size
+}
+
+// Spilled reports whether the captured output has been moved to disk
+func (b *Buffer) Spilled() bool {
+	return b.file != nil
+}
+
+// WriteTo writes the captured output to w
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	if b.file == nil {
+		return io.Copy(w, bytes.NewReader(b.mem.Bytes()))
+	}
+	return io.Copy(w, io.NewSectionReader(b.file, 0, b.size))
+}
+
+// ReadAll returns the captured output as a string
+func (b *Buffer) ReadAll() (string, error) {
+	var sb bytes.Buffer
+	if _, err := b.WriteTo(&sb); err != nil {
+		return "", fmt.Errorf("error reading captured output: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// Close releases the spill file, if any
+func (b *Buffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	b.file.Close()
+	b.file = nil
+	return os.Remove(name)
+}
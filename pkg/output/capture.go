@@ -0,0 +1,100 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// Capture records stdout and stderr separately, alongside an interleaved log of both in the order they were written
+type Capture struct {
+	Stdout   *Buffer
+	Stderr   *Buffer
+	Combined *Buffer
+
+	mu      sync.Mutex
+	console io.Writer
+}
+
+// NewCapture returns a Capture whose buffers keep up to limit bytes in memory before spilling to dir.
+// Everything written is also streamed to console.
+func NewCapture(limit int, dir string, console io.Writer) *Capture {
+	return &Capture{
+		Stdout:   NewBuffer(limit, dir),
+		Stderr:   NewBuffer(limit, dir),
+		Combined: NewBuffer(limit, dir),
+		console:  console,
+	}
+}
+
+// StdoutWriter returns the writer to attach to the command's stdout
+func (c *Capture) StdoutWriter() io.Writer {
+	return &streamWriter{capture: c, buf: c.Stdout}
+}
+
+// StderrWriter returns the writer to attach to the command's stderr
+func (c *Capture) StderrWriter() io.Writer {
+	return &streamWriter{capture: c, buf: c.Stderr}
+}
+
+// ReadAll returns the combined, stdout and stderr output captured so far
+func (c *Capture) ReadAll() (combined, stdout, stderr string, err error) {
+	if combined, err = c.Combined.ReadAll(); err != nil {
+		return "", "", "", err
+	}
+	if stdout, err = c.Stdout.ReadAll(); err != nil {
+		return "", "", "", err
+	}
+	if stderr, err = c.Stderr.ReadAll(); err != nil {
+		return "", "", "", err
+	}
+	return combined, stdout, stderr, nil
+}
+
+// Close releases the spill files of all buffers
+func (c *Capture) Close() error {
+	var firstErr error
+	for _, buf := range []*Buffer{c.Stdout, c.Stderr, c.Combined} {
+		if err := buf.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// streamWriter writes one stream to its own buffer, the combined log and the console
+type streamWriter struct {
+	capture *Capture
+	buf     *Buffer
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	c := w.capture
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := w.buf.Write(p); err != nil {
+		return 0, err
+	}
+	if _, err := c.Combined.Write(p); err != nil {
+		return 0, err
+	}
+	if c.console != nil {
+		if _, err := c.console.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// FileName returns the path of the captured output file for cmdName.
+// Commands run by path, e.g. ./lint.sh, are named after the file.
+func FileName(dir, cmdName string) string {
+	return fmt.Sprintf("%s/.output-%s.md", dir, filepath.Base(cmdName))
+}
+
+// StreamFileName returns the path of the captured stdout or stderr file for cmdName
+func StreamFileName(dir, cmdName, stream string) string {
+	return fmt.Sprintf("%s/.output-%s.%s.md", dir, filepath.Base(cmdName), stream)
+}
@@ -0,0 +1,178 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ansiPattern matches CSI sequences, OSC sequences terminated by BEL or ST, and other two-byte escapes
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+	// sgrPattern matches Select Graphic Rendition sequences, which set colours
+	sgrPattern = regexp.MustCompile(`\x1b\[([0-9;]*)m`)
+	// controlPattern matches C0 control characters other than tab, newline and carriage return
+	controlPattern = regexp.MustCompile(`[\x00-\x08\x0b\x0c\x0e-\x1f\x7f]`)
+)
+
+// NormalizeOptions controls how captured output is cleaned up before it is written
+type NormalizeOptions struct {
+	// ColorToDiff turns lines coloured green or red into diff + and - lines
+	ColorToDiff bool
+}
+
+// Normalize strips ANSI and OSC escape sequences, collapses carriage-return
+// progress lines to what a terminal would finally show and converts CRLF line
+// endings to LF.
+func Normalize(s string, opts NormalizeOptions) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		marker := ""
+		if opts.ColorToDiff {
+			marker = diffMarker(line)
+		}
+		line = ansiPattern.ReplaceAllString(line, "")
+		line = controlPattern.ReplaceAllString(line, "")
+		line = collapseCarriageReturns(line)
+		if marker != "" {
+			line = addDiffMarker(line, marker)
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NormalizeWriter normalizes output line by line as it is written, so output spilled to disk
+// can be normalized without reading it into memory
+type NormalizeWriter struct {
+	w    io.Writer
+	opts NormalizeOptions
+	line []byte
+}
+
+// NewNormalizeWriter returns a writer that writes the normalized output to w. Close it to write the last line.
+func NewNormalizeWriter(w io.Writer, opts NormalizeOptions) *NormalizeWriter {
+	return &NormalizeWriter{w: w, opts: opts}
+}
+
+// Write normalizes the complete lines in p and keeps the incomplete last one until the rest of it is written
+func (n *NormalizeWriter) Write(p []byte) (int, error) {
+	n.line = append(n.line, p...)
+	rest := n.line
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		if err := n.writeLine(rest[:i], "\n"); err != nil {
+			return 0, err
+		}
+		rest = rest[i+1:]
+	}
+	n.line = append(n.line[:0], rest...)
+	return len(p), nil
+}
+
+// Close writes the last line, which doesn't end with a newline
+func (n *NormalizeWriter) Close() error {
+	if len(n.line) == 0 {
+		return nil
+	}
+	err := n.writeLine(n.line, "")
+	n.line = n.line[:0]
+	return err
+}
+
+func (n *NormalizeWriter) writeLine(line []byte, end string) error {
+	// CRLF line endings are LF endings, not a redraw of the line
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	_, err := io.WriteString(n.w, Normalize(string(line), n.opts)+end)
+	return err
+}
+
+// ScanNormalized reports whether the normalized output captured in b is empty and whether it contains substr,
+// without reading it into memory
+func ScanNormalized(b *Buffer, opts NormalizeOptions, substr string) (empty, found bool, err error) {
+	m := &matchWriter{substr: []byte(substr)}
+	nw := NewNormalizeWriter(m, opts)
+	if _, err := b.WriteTo(nw); err != nil {
+		return false, false, err
+	}
+	if err := nw.Close(); err != nil {
+		return false, false, err
+	}
+	return m.size == 0, m.found, nil
+}
+
+// matchWriter discards what is written, recording its size and whether substr occurred, also across writes
+type matchWriter struct {
+	substr []byte
+	tail   []byte
+	size   int64
+	found  bool
+}
+
+func (m *matchWriter) Write(p []byte) (int, error) {
+	m.size += int64(len(p))
+	if m.found || len(m.substr) == 0 {
+		m.found = true
+		return len(p), nil
+	}
+	window := append(m.tail, p...)
+	if bytes.Contains(window, m.substr) {
+		m.found = true
+	}
+	if keep := len(m.substr) - 1; len(window) > keep {
+		window = window[len(window)-keep:]
+	}
+	m.tail = append(m.tail[:0], window...)
+	return len(p), nil
+}
+
+// collapseCarriageReturns overlays each carriage-return separated segment on the previous ones,
+// the way a terminal redraws a progress line
+func collapseCarriageReturns(line string) string {
+	if !strings.Contains(line, "\r") {
+		return line
+	}
+	var screen []rune
+	for _, segment := range strings.Split(line, "\r") {
+		for j, r := range []rune(segment) {
+			if j < len(screen) {
+				screen[j] = r
+			} else {
+				screen = append(screen, r)
+			}
+		}
+	}
+	return strings.TrimRight(string(screen), " ")
+}
+
+// diffMarker returns "+" or "-" when the first foreground colour set on the line is green or red
+func diffMarker(line string) string {
+	for _, match := range sgrPattern.FindAllStringSubmatch(line, -1) {
+		for _, param := range strings.Split(match[1], ";") {
+			switch param {
+			case "32", "92":
+				return "+"
+			case "31", "91":
+				return "-"
+			case "30", "33", "34", "35", "36", "37", "90", "93", "94", "95", "96", "97":
+				return ""
+			}
+		}
+	}
+	return ""
+}
+
+// addDiffMarker moves an indented marker to the start of the line, or prefixes the line with it
+func addDiffMarker(line, marker string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if strings.HasPrefix(trimmed, marker) {
+		indent := line[:len(line)-len(trimmed)]
+		return marker + indent + strings.TrimPrefix(trimmed, marker)
+	}
+	return marker + " " + line
+}
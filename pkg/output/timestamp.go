@@ -0,0 +1,37 @@
+package output
+
+import (
+	"io"
+	"time"
+)
+
+// TimestampWriter prefixes every line written through it with the current time
+type TimestampWriter struct {
+	w           io.Writer
+	now         func() time.Time
+	atLineStart bool
+}
+
+// NewTimestampWriter returns a TimestampWriter that writes to w
+func NewTimestampWriter(w io.Writer) *TimestampWriter {
+	return &TimestampWriter{w: w, now: time.Now, atLineStart: true}
+}
+
+// Write writes p to the underlying writer, inserting a timestamp at the start of each line
+func (t *TimestampWriter) Write(p []byte) (int, error) {
+	var line []byte
+	for _, c := range p {
+		if t.atLineStart {
+			line = append(line, t.now().Format("15:04:05.000 ")...)
+			t.atLineStart = false
+		}
+		line = append(line, c)
+		if c == '\n' {
+			t.atLineStart = true
+		}
+	}
+	if _, err := t.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,185 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// Status is the status of a commit for one context
+type Status struct {
+	State   State
+	Context string
+	// Description defaults to the state's default description and is cut to MaxDescriptionLength
+	Description string
+	TargetURL   string
+	// Summary is the markdown shown by reporters with a details page, such as check runs
+	Summary string
+	// Annotations are attached by reporters that support them, such as check runs
+	Annotations []*github.CheckRunAnnotation
+}
+
+// normalize validates s and fills in its default description
+func (s Status) normalize() (Status, error) {
+	if err := s.State.Validate(); err != nil {
+		return s, err
+	}
+	if s.Context == "" {
+		return s, fmt.Errorf("status context is required")
+	}
+	if s.Description == "" {
+		s.Description = s.State.DefaultDescription()
+	}
+	s.Description = truncateDescription(s.Description)
+	return s, nil
+}
+
+// Reporter reports the status of a commit
+type Reporter interface {
+	Report(ctx context.Context, s Status) error
+}
+
+// CommitStatusReporter reports statuses as GitHub commit statuses
+type CommitStatusReporter struct {
+	client      *github.Client
+	owner, repo string
+	sha         string
+}
+
+// NewCommitStatusReporter returns a reporter that posts commit statuses on sha
+func NewCommitStatusReporter(client *github.Client, owner, repo, sha string) *CommitStatusReporter {
+	return &CommitStatusReporter{client: client, owner: owner, repo: repo, sha: sha}
+}
+
+// Report posts s as a commit status
+func (r *CommitStatusReporter) Report(ctx context.Context, s Status) error {
+	s, err := s.normalize()
+	if err != nil {
+		return err
+	}
+	state := string(s.State)
+	repoStatus := &github.RepoStatus{
+		State:       &state,
+		Description: &s.Description,
+		Context:     &s.Context,
+	}
+	if s.TargetURL != "" {
+		repoStatus.TargetURL = &s.TargetURL
+	}
+	_, _, err = r.client.Repositories.CreateStatus(ctx, r.owner, r.repo, r.sha, repoStatus)
+	if err != nil {
+		return fmt.Errorf("error creating commit status: %w", err)
+	}
+	fmt.Printf("Commit status posted: %s (%s)\n", state, s.Description)
+	return nil
+}
+
+// maxAnnotationsPerRequest is the number of annotations the checks API accepts per request
+const maxAnnotationsPerRequest = 50
+
+// CheckRunReporter reports statuses as check runs, named after the status context.
+// A pending status starts a check run that the context's next status completes.
+type CheckRunReporter struct {
+	client      *github.Client
+	owner, repo string
+	sha         string
+
+	mu sync.Mutex
+	// running are the IDs of the started check runs, keyed by context
+	running map[string]int64
+}
+
+// NewCheckRunReporter returns a reporter that posts check runs on sha
+func NewCheckRunReporter(client *github.Client, owner, repo, sha string) *CheckRunReporter {
+	return &CheckRunReporter{client: client, owner: owner, repo: repo, sha: sha, running: map[string]int64{}}
+}
+
+// Report creates or completes the check run for s's context, adding annotations in batches the checks API accepts
+func (r *CheckRunReporter) Report(ctx context.Context, s Status) error {
+	s, err := s.normalize()
+	if err != nil {
+		return err
+	}
+	runStatus, conclusion := s.State.checkRun()
+	summary := s.Summary
+	if summary == "" {
+		summary = s.Description
+	}
+	summary = TruncateSummary(summary, "_The summary was truncated._")
+	batch := s.Annotations
+	if len(batch) > maxAnnotationsPerRequest {
+		batch = batch[:maxAnnotationsPerRequest]
+	}
+	output := &github.CheckRunOutput{
+		Title:       github.String(s.Description),
+		Summary:     github.String(summary),
+		Annotations: batch,
+	}
+	var detailsURL *string
+	if s.TargetURL != "" {
+		detailsURL = github.String(s.TargetURL)
+	}
+	var optionalConclusion *string
+	if conclusion != "" {
+		optionalConclusion = github.String(conclusion)
+	}
+
+	r.mu.Lock()
+	id, started := r.running[s.Context]
+	r.mu.Unlock()
+	if started {
+		_, _, err = r.client.Checks.UpdateCheckRun(ctx, r.owner, r.repo, id, github.UpdateCheckRunOptions{
+			Name:       s.Context,
+			DetailsURL: detailsURL,
+			Status:     github.String(runStatus),
+			Conclusion: optionalConclusion,
+			Output:     output,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating check run: %w", err)
+		}
+	} else {
+		checkRun, _, err := r.client.Checks.CreateCheckRun(ctx, r.owner, r.repo, github.CreateCheckRunOptions{
+			Name:       s.Context,
+			HeadSHA:    r.sha,
+			DetailsURL: detailsURL,
+			Status:     github.String(runStatus),
+			Conclusion: optionalConclusion,
+			Output:     output,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating check run: %w", err)
+		}
+		id = checkRun.GetID()
+	}
+
+	r.mu.Lock()
+	if conclusion == "" {
+		r.running[s.Context] = id
+	} else {
+		delete(r.running, s.Context)
+	}
+	r.mu.Unlock()
+
+	for start := maxAnnotationsPerRequest; start < len(s.Annotations); start += maxAnnotationsPerRequest {
+		end := start + maxAnnotationsPerRequest
+		if end > len(s.Annotations) {
+			end = len(s.Annotations)
+		}
+		_, _, err := r.client.Checks.UpdateCheckRun(ctx, r.owner, r.repo, id, github.UpdateCheckRunOptions{
+			Name: s.Context,
+			Output: &github.CheckRunOutput{
+				Title:       output.Title,
+				Summary:     output.Summary,
+				Annotations: s.Annotations[start:end],
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error adding check run annotations: %w", err)
+		}
+	}
+	fmt.Printf("Check run posted: %s (%d annotations)\n", s.State, len(s.Annotations))
+	return nil
+}
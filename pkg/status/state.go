@@ -0,0 +1,65 @@
+package status
+
+import "fmt"
+
+// State is the state of a commit status
+type State string
+
+// The states GitHub accepts for a commit status
+const (
+	StatePending State = "pending"
+	StateSuccess State = "success"
+	StateFailure State = "failure"
+	StateError   State = "error"
+)
+
+// States are all valid states
+var States = []State{StatePending, StateSuccess, StateFailure, StateError}
+
+// ParseState parses a state name such as "success"
+func ParseState(s string) (State, error) {
+	state := State(s)
+	if err := state.Validate(); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// Validate returns an error unless s is one of States
+func (s State) Validate() error {
+	for _, state := range States {
+		if s == state {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid commit state: %q (expected pending, success, failure or error)", string(s))
+}
+
+// DefaultDescription is the description posted for s when the run has none of its own
+func (s State) DefaultDescription() string {
+	switch s {
+	case StatePending:
+		return "In Progress"
+	case StateSuccess:
+		return "Success"
+	case StateFailure:
+		return "Failed"
+	case StateError:
+		return "Error"
+	}
+	return ""
+}
+
+// checkRun maps s onto a check run's status and, once completed, its conclusion
+func (s State) checkRun() (runStatus, conclusion string) {
+	switch s {
+	case StatePending:
+		return "in_progress", ""
+	case StateSuccess:
+		return "completed", "success"
+	case StateError:
+		// Check runs have no error conclusion; action_required asks for a look at the run rather than the code
+		return "completed", "action_required"
+	}
+	return "completed", "failure"
+}
@@ -1,31 +1,112 @@
 package status
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"strings"
+	"text/template"
+	"unicode/utf8"
 
 	"github.com/google/go-github/v41/github"
 )
 
-// PostCommitStatus posts the GitHub commit status
-func PostCommitStatus(ctx context.Context, client *github.Client, owner, repo, sha, state, context string) error {
-	commitState := strings.ToUpper(string(state[0])) + state[1:]
-	if state == "failure" {
-		commitState = "Failed"
+// MaxDescriptionLength is the longest commit status description GitHub accepts
+const MaxDescriptionLength = 140
+
+// MaxSummaryLength is the longest check run summary GitHub accepts
+const MaxSummaryLength = 65535
+
+// DescriptionData is what a status description template can refer to
+type DescriptionData struct {
+	State     State
+	Command   string
+	Project   string
+	Workspace string
+	// Summary is the run's own description, e.g. "tflint: 3 issues (1 error)", or the default one for State
+	Summary string
+}
+
+// LinkCommitStatus points the latest status of context on sha at targetURL, keeping its state and description
+func LinkCommitStatus(ctx context.Context, client *github.Client, owner, repo, sha, context, targetURL string) error {
+	// Statuses are listed newest first
+	statuses, _, err := client.Repositories.ListStatuses(ctx, owner, repo, sha, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return fmt.Errorf("error listing commit statuses: %w", err)
 	}
-	if state == "pending" {
-		commitState = "In Progress"
+	for _, s := range statuses {
+		if s.GetContext() != context {
+			continue
+		}
+		if s.GetTargetURL() == targetURL {
+			return nil
+		}
+		return NewCommitStatusReporter(client, owner, repo, sha).Report(ctx, Status{
+			State:       State(s.GetState()),
+			Context:     context,
+			Description: s.GetDescription(),
+			TargetURL:   targetURL,
+		})
 	}
-	status := &github.RepoStatus{
-		State:       &state,
-		Description: &commitState,
-		Context:     &context,
+	return nil
+}
+
+// GetCombinedStatus returns the combined state of sha and the latest status of every context
+func GetCombinedStatus(ctx context.Context, client *github.Client, owner, repo, sha string) (*github.CombinedStatus, error) {
+	var combined *github.CombinedStatus
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Repositories.GetCombinedStatus(ctx, owner, repo, sha, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting combined status: %w", err)
+		}
+		if combined == nil {
+			combined = page
+		} else {
+			combined.Statuses = append(combined.Statuses, page.Statuses...)
+		}
+		if resp.NextPage == 0 {
+			return combined, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// RenderDescription renders a status description template, e.g. "{{.Project}}: {{.Summary}}".
+// An empty template renders the summary.
+func RenderDescription(tmpl string, data DescriptionData) (string, error) {
+	if tmpl == "" {
+		return data.Summary, nil
 	}
-	_, _, err := client.Repositories.CreateStatus(ctx, owner, repo, sha, status)
+	t, err := template.New("description").Parse(tmpl)
 	if err != nil {
-		return fmt.Errorf("error creating commit status: %w", err)
+		return "", fmt.Errorf("error parsing status description template: %w", err)
 	}
-	fmt.Printf("Commit status posted: %s\n", commitState)
-	return nil
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering status description: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// truncateDescription shortens a description to MaxDescriptionLength characters, marking the cut with an ellipsis
+func truncateDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= MaxDescriptionLength {
+		return description
+	}
+	return string(runes[:MaxDescriptionLength-1]) + "…"
+}
+
+// TruncateSummary shortens a check run summary to MaxSummaryLength, ending it with note when it's cut.
+// Bytes are counted, so multi-byte output stays within the limit too.
+func TruncateSummary(summary, note string) string {
+	if len(summary) <= MaxSummaryLength {
+		return summary
+	}
+	suffix := "…\n\n" + note
+	cut := MaxSummaryLength - len(suffix)
+	for cut > 0 && !utf8.RuneStart(summary[cut]) {
+		cut--
+	}
+	return summary[:cut] + suffix
 }
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gh-pr-commenter/pkg/findings"
+)
+
+// Local stores results as JSON files in a directory, one subdirectory per commit
+type Local struct {
+	Dir string
+}
+
+// NewLocal opens a local store in dir
+func NewLocal(dir string) (Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("local result store needs a directory")
+	}
+	return &Local{Dir: dir}, nil
+}
+
+// path returns the file the results of key are stored in
+func (l *Local) path(key Key) string {
+	name := key.Kind
+	if key.Project != "" {
+		name += "-" + key.Project
+	}
+	return filepath.Join(l.Dir, key.SHA, name+".json")
+}
+
+// Save writes the results of key, replacing earlier ones
+func (l *Local) Save(key Key, results []findings.Finding) error {
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating result store directory: %w", err)
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("error encoding results: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing results: %w", err)
+	}
+	return nil
+}
+
+// Load reads the results of key
+func (l *Local) Load(key Key) ([]findings.Finding, error) {
+	data, err := os.ReadFile(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading results: %w", err)
+	}
+	var results []findings.Finding
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("error parsing results: %w", err)
+	}
+	return results, nil
+}
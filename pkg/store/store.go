@@ -0,0 +1,53 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gh-pr-commenter/pkg/findings"
+)
+
+// ErrNotFound is returned when no results are stored for a commit
+var ErrNotFound = errors.New("no stored results")
+
+// Key identifies a run's results: the commit it ran on, the report kind and the project
+type Key struct {
+	SHA     string
+	Kind    string
+	Project string
+}
+
+// Store saves and loads the findings of runs, keyed by commit
+type Store interface {
+	Save(key Key, results []findings.Finding) error
+	// Load returns ErrNotFound when the key has no stored results
+	Load(key Key) ([]findings.Finding, error)
+}
+
+// Factory opens a store at a location, e.g. a directory
+type Factory func(location string) (Store, error)
+
+// factories are the registered store implementations, keyed by URL scheme
+var factories = map[string]Factory{
+	"file": NewLocal,
+}
+
+// Register makes a store implementation available under a URL scheme
+func Register(scheme string, factory Factory) {
+	factories[scheme] = factory
+}
+
+// Open opens the store for a URL such as file:///var/ghpc/results.
+// A URL without a scheme is a local directory.
+func Open(url string) (Store, error) {
+	scheme, location, ok := strings.Cut(url, "://")
+	if !ok {
+		return NewLocal(url)
+	}
+	factory, ok := factories[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown result store scheme: %s", scheme)
+	}
+	return factory(location)
+}
@@ -0,0 +1,95 @@
+package terraform
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// preventDestroyTagPattern matches a prevent_destroy tag being removed in a text plan
+var preventDestroyTagPattern = regexp.MustCompile(`"?prevent_destroy"?\s*=\s*"?true"?`)
+
+// Guard detects destroyed or replaced resources that are protected
+type Guard struct {
+	// Patterns are globs matched against resource addresses, e.g. aws_db_instance.*
+	Patterns []string
+}
+
+// Violations returns the destroyed or replaced resources that match a pattern or carry a prevent_destroy tag
+func (g Guard) Violations(plan *Plan) []ResourceChange {
+	var violations []ResourceChange
+	for _, rc := range plan.Resources {
+		if rc.Action != ActionDelete && rc.Action != ActionReplace {
+			continue
+		}
+		if g.matches(rc.Address) || hasPreventDestroyTag(rc) {
+			violations = append(violations, rc)
+		}
+	}
+	return violations
+}
+
+// matches reports whether address, with or without its module path, matches one of the patterns
+func (g Guard) matches(address string) bool {
+	local := localAddress(address)
+	for _, pattern := range g.Patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		for _, candidate := range []string{address, local} {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// localAddress strips the module path from an address, e.g. module.db.aws_db_instance.main becomes aws_db_instance.main
+func localAddress(address string) string {
+	for strings.HasPrefix(address, "module.") {
+		rest := strings.TrimPrefix(address, "module.")
+		idx := strings.Index(rest, ".")
+		if idx < 0 {
+			return address
+		}
+		address = rest[idx+1:]
+	}
+	return address
+}
+
+// hasPreventDestroyTag reports whether the resource was tagged prevent_destroy before the change
+func hasPreventDestroyTag(change ResourceChange) bool {
+	if change.Before != nil {
+		for _, key := range []string{"tags", "tags_all"} {
+			tags, ok := change.Before[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if value, ok := tags["prevent_destroy"]; ok && fmt.Sprint(value) == "true" {
+				return true
+			}
+		}
+		return false
+	}
+	return preventDestroyTagPattern.MatchString(change.Diff)
+}
+
+// GuardBanner renders the warning shown at the top of the comment when protected resources are destroyed
+func GuardBanner(violations []ResourceChange, overrideLabel string, overridden bool) string {
+	var sb strings.Builder
+	sb.WriteString("> [!WARNING]\n")
+	fmt.Fprintf(&sb, "> **Destructive change guard:** %d protected resource(s) will be destroyed or replaced:\n", len(violations))
+	for _, rc := range violations {
+		fmt.Fprintf(&sb, "> - `%s` %s\n", rc.Address, actionLabels[rc.Action])
+	}
+	if overridden {
+		fmt.Fprintf(&sb, ">\n> The `%s` label is present, so the change is allowed.\n", overrideLabel)
+	} else if overrideLabel != "" {
+		fmt.Fprintf(&sb, ">\n> Add the `%s` label to the pull request and re-run to allow it.\n", overrideLabel)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,275 @@
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action is what a plan will do to a resource
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionReplace Action = "replace"
+	ActionRead    Action = "read"
+	ActionNoOp    Action = "no-op"
+)
+
+// ErrNoPlan is returned when the output doesn't contain a terraform plan
+var ErrNoPlan = errors.New("no terraform plan found in output")
+
+// ResourceChange is a planned change to a single resource
+type ResourceChange struct {
+	Address string
+	Type    string
+	Action  Action
+	// Diff is the plan's rendering of the change
+	Diff string
+	// Before and After are the resource's attributes, only available from JSON plans
+	Before map[string]interface{}
+	After  map[string]interface{}
+}
+
+// Plan is a parsed terraform plan
+type Plan struct {
+	Resources []ResourceChange
+}
+
+var (
+	resourceHeaderPattern = regexp.MustCompile(`^\s*# (\S+)(?: \(moved from \S+\))? (?:will be created|will be destroyed|will be updated in-place|must be replaced|will be replaced, as requested|is tainted, so must be replaced|will be read during apply|has moved to \S+)`)
+	summaryPattern        = regexp.MustCompile(`^\s*Plan: \d+ to import, \d+ to add, \d+ to change, \d+ to destroy\.|^\s*Plan: \d+ to add, \d+ to change, \d+ to destroy\.`)
+	noChangesPattern      = regexp.MustCompile(`^\s*No changes\.`)
+)
+
+// ParsePlan parses either the JSON output of `terraform show -json` or the text output of `terraform plan`
+func ParsePlan(output string) (*Plan, error) {
+	trimmed := strings.TrimSpace(output)
+	if strings.HasPrefix(trimmed, "{") {
+		return ParsePlanJSON([]byte(trimmed))
+	}
+	return ParsePlanText(output)
+}
+
+// ParsePlanJSON parses the output of `terraform show -json <planfile>`
+func ParsePlanJSON(data []byte) (*Plan, error) {
+	var raw struct {
+		FormatVersion   string `json:"format_version"`
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Type    string `json:"type"`
+			Change  struct {
+				Actions      []string               `json:"actions"`
+				Before       map[string]interface{} `json:"before"`
+				After        map[string]interface{} `json:"after"`
+				AfterUnknown map[string]interface{} `json:"after_unknown"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing plan JSON: %w", err)
+	}
+	if raw.FormatVersion == "" {
+		return nil, ErrNoPlan
+	}
+
+	plan := &Plan{}
+	for _, rc := range raw.ResourceChanges {
+		action := actionFromJSON(rc.Change.Actions)
+		if action == ActionNoOp {
+			continue
+		}
+		change := ResourceChange{
+			Address: rc.Address,
+			Type:    rc.Type,
+			Action:  action,
+			Before:  rc.Change.Before,
+			After:   rc.Change.After,
+		}
+		change.Diff = attributeDiff(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown)
+		plan.Resources = append(plan.Resources, change)
+	}
+	return plan, nil
+}
+
+// actionFromJSON maps the JSON plan's action list to a single Action
+func actionFromJSON(actions []string) Action {
+	switch strings.Join(actions, ",") {
+	case "create":
+		return ActionCreate
+	case "update":
+		return ActionUpdate
+	case "delete":
+		return ActionDelete
+	case "delete,create", "create,delete":
+		return ActionReplace
+	case "read":
+		return ActionRead
+	default:
+		return ActionNoOp
+	}
+}
+
+// attributeDiff renders the attributes that differ between before and after as diff lines
+func attributeDiff(before, after, afterUnknown map[string]interface{}) string {
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	for k := range afterUnknown {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var sb strings.Builder
+	for _, k := range sorted {
+		oldValue, hadOld := before[k]
+		newValue, hasNew := after[k]
+		if unknown, ok := afterUnknown[k].(bool); ok && unknown {
+			newValue, hasNew = "(known after apply)", true
+		}
+		oldText, newText := formatValue(oldValue), formatValue(newValue)
+		if hadOld && hasNew && oldText == newText {
+			continue
+		}
+		if hadOld && oldValue != nil {
+			fmt.Fprintf(&sb, "- %s = %s\n", k, oldText)
+		}
+		if hasNew && newValue != nil {
+			fmt.Fprintf(&sb, "+ %s = %s\n", k, newText)
+		}
+	}
+	return sb.String()
+}
+
+// formatValue renders an attribute value the way terraform would show it
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		if s == "(known after apply)" {
+			return s
+		}
+		return fmt.Sprintf("%q", s)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// ParsePlanText parses the human-readable output of `terraform plan`
+func ParsePlanText(output string) (*Plan, error) {
+	plan := &Plan{}
+	found := false
+	var current *ResourceChange
+	var body []string
+
+	flush := func() {
+		if current != nil {
+			current.Diff = strings.Join(body, "\n")
+			plan.Resources = append(plan.Resources, *current)
+			current = nil
+			body = nil
+		}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if summaryPattern.MatchString(line) || noChangesPattern.MatchString(line) {
+			flush()
+			found = true
+			continue
+		}
+		if m := resourceHeaderPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			action := actionFromText(line)
+			if action == ActionNoOp {
+				continue
+			}
+			current = &ResourceChange{Address: m[1], Type: resourceType(m[1]), Action: action}
+			continue
+		}
+		if current != nil {
+			body = append(body, line)
+			// A resource block ends at its closing brace, nested blocks are indented further
+			if strings.TrimRight(line, " ") == "    }" {
+				flush()
+			}
+		}
+	}
+	flush()
+
+	if !found {
+		return nil, ErrNoPlan
+	}
+	return plan, nil
+}
+
+// actionFromText maps a text plan resource header to an Action
+func actionFromText(header string) Action {
+	switch {
+	case strings.Contains(header, "will be created"):
+		return ActionCreate
+	case strings.Contains(header, "will be destroyed"):
+		return ActionDelete
+	case strings.Contains(header, "will be updated in-place"):
+		return ActionUpdate
+	case strings.Contains(header, "must be replaced"), strings.Contains(header, "will be replaced"):
+		return ActionReplace
+	case strings.Contains(header, "will be read during apply"):
+		return ActionRead
+	default:
+		return ActionNoOp
+	}
+}
+
+// resourceType extracts the resource type from an address such as module.db.aws_db_instance.main[0] or data.aws_ami.ubuntu
+func resourceType(address string) string {
+	parts := strings.Split(address, ".")
+	for i := 0; i < len(parts)-1; i++ {
+		switch parts[i] {
+		case "module":
+			i++
+			continue
+		case "data":
+			return parts[i+1]
+		}
+		return parts[i]
+	}
+	return ""
+}
+
+// Counts returns the number of resources to add, change and destroy. Replacements count as both an add and a destroy.
+func (p *Plan) Counts() (add, change, destroy int) {
+	for _, rc := range p.Resources {
+		switch rc.Action {
+		case ActionCreate:
+			add++
+		case ActionUpdate:
+			change++
+		case ActionDelete:
+			destroy++
+		case ActionReplace:
+			add++
+			destroy++
+		}
+	}
+	return add, change, destroy
+}
+
+// StatusDescription returns a short summary of the plan such as "+3 ~1 -0"
+func (p *Plan) StatusDescription() string {
+	add, change, destroy := p.Counts()
+	return fmt.Sprintf("+%d ~%d -%d", add, change, destroy)
+}
@@ -0,0 +1,86 @@
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// diffMarkerPattern matches the change marker at the start of a text plan line
+var diffMarkerPattern = regexp.MustCompile(`^(\s*)(-/\+|\+/-|[-+~])(\s|$)`)
+
+// actionLabels describes each action in a resource's summary line
+var actionLabels = map[Action]string{
+	ActionCreate:  "will be created",
+	ActionUpdate:  "will be updated in-place",
+	ActionDelete:  "will be destroyed",
+	ActionReplace: "will be replaced",
+	ActionRead:    "will be read during apply",
+}
+
+// Markdown renders the plan as a summary header followed by a collapsible section per resource.
+// Destroyed and replaced resources are listed first, expanded and called out in a warning.
+func (p *Plan) Markdown() string {
+	add, change, destroy := p.Counts()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### Terraform plan: %d to add, %d to change, %d to destroy\n\n", add, change, destroy)
+	if len(p.Resources) == 0 {
+		sb.WriteString("No changes. Your infrastructure matches the configuration.\n")
+		return sb.String()
+	}
+
+	var destructive, others []ResourceChange
+	for _, rc := range p.Resources {
+		if rc.Action == ActionDelete || rc.Action == ActionReplace {
+			destructive = append(destructive, rc)
+		} else {
+			others = append(others, rc)
+		}
+	}
+
+	if len(destructive) > 0 {
+		sb.WriteString("> [!CAUTION]\n")
+		fmt.Fprintf(&sb, "> %d resource(s) will be destroyed or replaced:\n", len(destructive))
+		for _, rc := range destructive {
+			fmt.Fprintf(&sb, "> - `%s` %s\n", rc.Address, actionLabels[rc.Action])
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, rc := range append(destructive, others...) {
+		sb.WriteString(resourceSection(rc))
+	}
+	return sb.String()
+}
+
+// resourceSection renders one resource change as a collapsible diff block
+func resourceSection(rc ResourceChange) string {
+	open := ""
+	if rc.Action == ActionDelete || rc.Action == ActionReplace {
+		open = " open"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<details%s><summary><code>%s</code> %s</summary>\n\n", open, rc.Address, actionLabels[rc.Action])
+	sb.WriteString("```diff\n")
+	sb.WriteString(diffFormat(rc.Diff))
+	sb.WriteString("\n```\n</details>\n\n")
+	return sb.String()
+}
+
+// diffFormat moves each line's change marker to the first column so GitHub highlights it,
+// using ! for in-place updates and replacements
+func diffFormat(diff string) string {
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	for i, line := range lines {
+		m := diffMarkerPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		marker := m[2]
+		if marker != "+" && marker != "-" {
+			marker = "!"
+		}
+		lines[i] = marker + m[1] + strings.Repeat(" ", len(m[2])-1) + line[len(m[1])+len(m[2]):]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,199 @@
+package trivy
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+
+	"gh-pr-commenter/pkg/findings"
+)
+
+//go:embed report.tmpl
+var reportTemplate string
+
+// Report is a trivy JSON report, as written by `trivy <target> --format json`
+type Report struct {
+	SchemaVersion int
+	ArtifactName  string
+	Results       []Result
+}
+
+// Result is the scan result for one target, such as a file or an image layer
+type Result struct {
+	Target            string
+	Class             string
+	Type              string
+	Vulnerabilities   []Vulnerability
+	Misconfigurations []Misconfiguration
+	Secrets           []Secret
+}
+
+// Vulnerability is a known vulnerability in an installed package
+type Vulnerability struct {
+	VulnerabilityID  string
+	PkgName          string
+	InstalledVersion string
+	FixedVersion     string
+	Severity         string
+	Title            string
+	PrimaryURL       string
+}
+
+// Misconfiguration is a failed configuration check
+type Misconfiguration struct {
+	Type          string
+	ID            string
+	Title         string
+	Message       string
+	Severity      string
+	PrimaryURL    string
+	Status        string
+	CauseMetadata struct {
+		StartLine int
+	}
+}
+
+// Secret is a secret found in a file
+type Secret struct {
+	RuleID    string
+	Category  string
+	Severity  string
+	Title     string
+	StartLine int
+}
+
+// ParseReport parses a trivy JSON report. Passed misconfiguration checks are dropped.
+func ParseReport(data []byte) (*Report, error) {
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("error parsing trivy JSON: %w", err)
+	}
+	if report.SchemaVersion == 0 {
+		return nil, fmt.Errorf("error parsing trivy JSON: no SchemaVersion field")
+	}
+	for i, result := range report.Results {
+		var failed []Misconfiguration
+		for _, m := range result.Misconfigurations {
+			if m.Status == "" || m.Status == "FAIL" {
+				failed = append(failed, m)
+			}
+		}
+		report.Results[i].Misconfigurations = failed
+	}
+	return &report, nil
+}
+
+// Merge appends the results of other to r
+func (r *Report) Merge(other *Report) {
+	r.Results = append(r.Results, other.Results...)
+}
+
+// Severities returns the severity of every vulnerability, misconfiguration and secret in the result
+func (r Result) Severities() []string {
+	var severities []string
+	for _, v := range r.Vulnerabilities {
+		severities = append(severities, v.Severity)
+	}
+	for _, m := range r.Misconfigurations {
+		severities = append(severities, m.Severity)
+	}
+	for _, s := range r.Secrets {
+		severities = append(severities, s.Severity)
+	}
+	return severities
+}
+
+// Findings converts the report into the common finding model
+func (r *Report) Findings() []findings.Finding {
+	var all []findings.Finding
+	for _, result := range r.Results {
+		for _, v := range result.Vulnerabilities {
+			all = append(all, v.finding(result.Target))
+		}
+		for _, m := range result.Misconfigurations {
+			all = append(all, m.finding(result.Target))
+		}
+		for _, s := range result.Secrets {
+			all = append(all, s.finding(result.Target))
+		}
+	}
+	return all
+}
+
+// Filter returns a copy of the report with only the items whose finding keep accepts.
+// Results left without items are kept, so a scan whose findings are all filtered out still renders as passed.
+func (r *Report) Filter(keep func(f findings.Finding) bool) *Report {
+	filtered := &Report{SchemaVersion: r.SchemaVersion, ArtifactName: r.ArtifactName}
+	for _, result := range r.Results {
+		kept := Result{Target: result.Target, Class: result.Class, Type: result.Type}
+		for _, v := range result.Vulnerabilities {
+			if keep(v.finding(result.Target)) {
+				kept.Vulnerabilities = append(kept.Vulnerabilities, v)
+			}
+		}
+		for _, m := range result.Misconfigurations {
+			if keep(m.finding(result.Target)) {
+				kept.Misconfigurations = append(kept.Misconfigurations, m)
+			}
+		}
+		for _, s := range result.Secrets {
+			if keep(s.finding(result.Target)) {
+				kept.Secrets = append(kept.Secrets, s)
+			}
+		}
+		filtered.Results = append(filtered.Results, kept)
+	}
+	return filtered
+}
+
+func (v Vulnerability) finding(target string) findings.Finding {
+	return findings.Finding{
+		Tool:     "trivy",
+		Rule:     v.VulnerabilityID,
+		Severity: findings.NewSeverity(v.Severity),
+		File:     target,
+		Message:  fmt.Sprintf("%s %s: %s", v.PkgName, v.InstalledVersion, v.Title),
+	}
+}
+
+func (m Misconfiguration) finding(target string) findings.Finding {
+	return findings.Finding{
+		Tool:     "trivy",
+		Rule:     m.ID,
+		Severity: findings.NewSeverity(m.Severity),
+		File:     target,
+		Line:     m.CauseMetadata.StartLine,
+		Message:  m.Message,
+	}
+}
+
+func (s Secret) finding(target string) findings.Finding {
+	return findings.Finding{
+		Tool:     "trivy",
+		Rule:     s.RuleID,
+		Severity: findings.NewSeverity(s.Severity),
+		File:     target,
+		Line:     s.StartLine,
+		Message:  s.Title,
+	}
+}
+
+// Markdown renders the report with the built-in template
+func (r *Report) Markdown() (string, error) {
+	tmpl, err := template.New("trivy").Funcs(template.FuncMap{
+		"escapeXML": html.EscapeString,
+		"add":       func(a, b int) int { return a + b },
+	}).Parse(reportTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing trivy template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r); err != nil {
+		return "", fmt.Errorf("error rendering trivy report: %w", err)
+	}
+	return strings.TrimSpace(buf.String()) + "\n", nil
+}
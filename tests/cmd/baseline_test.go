@@ -0,0 +1,121 @@
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/google/go-github/v41/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/machinebox/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+	var bodies []string
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		func(req *http.Request) (*http.Response, error) {
+			var comment github.IssueComment
+			if err := json.NewDecoder(req.Body).Decode(&comment); err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, comment.GetBody())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("GH_STATUS_CONTEXT", "")
+	dir := t.TempDir()
+	t.Setenv("TMP_GHPC_DIR", dir)
+	t.Setenv("RESULT_STORE", filepath.Join(dir, "results"))
+	baselineFile := filepath.Join(dir, "baseline.json")
+	os.Setenv("BASELINE_FILE", baselineFile)
+	defer os.Unsetenv("BASELINE_FILE")
+
+	// Record a report where one of the current findings is already fixed and another one isn't there yet
+	old := `{"issues":[
+{"rule":{"name":"terraform_unused_declarations","severity":"warning"},"message":"variable \"region\" is declared but not used","range":{"filename":"variables.tf","start":{"line":1}}},
+{"rule":{"name":"terraform_deprecated_interpolation","severity":"notice"},"message":"Interpolation-only expressions are deprecated","range":{"filename":"main.tf","start":{"line":2}}},
+{"rule":{"name":"terraform_required_version","severity":"warning"},"message":"terraform \"required_version\" attribute is required","range":{"filename":"main.tf","start":{"line":1}}}
+],"errors":[]}`
+	oldReport := filepath.Join(dir, "tflint.json")
+	assert.NoError(t, os.WriteFile(oldReport, []byte(old), 0644))
+	assert.NoError(t, cmd.Baseline("tflint", []string{oldReport}))
+
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"failure: tflint: 1 issue (1 error)"}, statuses)
+	assert.Len(t, bodies, 1)
+	assert.Contains(t, bodies[0], "**Baseline:** 1 new, 1 fixed, 2 known findings not reported")
+	assert.Contains(t, bodies[0], "aws_instance_invalid_type")
+	assert.NotContains(t, bodies[0], "terraform_unused_declarations")
+
+	err = cmd.Baseline("junit", []string{"../pkg/junit/testdata/report.xml"})
+	assert.Error(t, err)
+}
+
+func TestBaseline_OnlyChanged(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+	// Only variables.tf is changed, so the findings in main.tf aren't reported
+	server.SetFiles(123, []*github.CommitFile{{Filename: github.String("variables.tf"), Patch: github.String("@@ -1,1 +1,3 @@\n+\n+\n variable \"region\" {}")}})
+
+	dir := t.TempDir()
+	t.Setenv("HEAD_COMMIT", "test-commit")
+	t.Setenv("BASE_REPO_OWNER", "test-owner")
+	t.Setenv("BASE_REPO_NAME", "test-repo")
+	t.Setenv("PULL_NUM", "123")
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("GH_STATUS_CONTEXT", "")
+	t.Setenv("TMP_GHPC_DIR", dir)
+	t.Setenv("RESULT_STORE", filepath.Join(dir, "results"))
+	t.Setenv("ONLY_CHANGED", "files")
+	baselineFile := filepath.Join(dir, "baseline.json")
+	t.Setenv("BASELINE_FILE", baselineFile)
+
+	old := `{"issues":[
+{"rule":{"name":"terraform_unused_declarations","severity":"warning"},"message":"variable \"region\" is declared but not used","range":{"filename":"variables.tf","start":{"line":1}}},
+{"rule":{"name":"terraform_deprecated_interpolation","severity":"notice"},"message":"Interpolation-only expressions are deprecated","range":{"filename":"main.tf","start":{"line":2}}},
+{"rule":{"name":"terraform_required_version","severity":"warning"},"message":"terraform \"required_version\" attribute is required","range":{"filename":"main.tf","start":{"line":1}}}
+],"errors":[]}`
+	oldReport := filepath.Join(dir, "tflint.json")
+	assert.NoError(t, os.WriteFile(oldReport, []byte(old), 0644))
+	assert.NoError(t, cmd.Baseline("tflint", []string{oldReport}))
+
+	err := cmd.Report(context.Background(), server.Client(), server.GraphQLClient(), "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+	comments := server.Comments(123)
+	if assert.Len(t, comments, 1) {
+		// The known finding in main.tf is outside the diff, but still isn't counted as fixed
+		assert.Contains(t, comments[0].Body, "**Baseline:** 1 new, 1 fixed, 2 known findings not reported")
+		assert.NotContains(t, comments[0].Body, "aws_instance_invalid_type")
+	}
+}
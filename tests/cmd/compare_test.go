@@ -0,0 +1,91 @@
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"github.com/google/go-github/v41/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/machinebox/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_CompareBase(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	for _, sha := range []string{"base-commit", "test-commit"} {
+		httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/"+sha,
+			func(req *http.Request) (*http.Response, error) {
+				var status github.RepoStatus
+				if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+					return nil, err
+				}
+				statuses = append(statuses, status.GetState()+": "+status.GetDescription())
+				return httpmock.NewStringResponse(201, `{}`), nil
+			})
+	}
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/pulls/123",
+		httpmock.NewStringResponder(200, `{"number": 123, "base": {"sha": "base-commit"}}`))
+	var bodies []string
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		func(req *http.Request) (*http.Response, error) {
+			var comment github.IssueComment
+			if err := json.NewDecoder(req.Body).Decode(&comment); err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, comment.GetBody())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("GH_STATUS_CONTEXT", "")
+	dir := t.TempDir()
+	t.Setenv("TMP_GHPC_DIR", dir)
+	t.Setenv("RESULT_STORE", filepath.Join(dir, "results"))
+	defer os.Unsetenv("COMPARE_BASE")
+
+	// The base commit's run has one finding that the PR fixes and one that it keeps
+	base := `{"issues":[
+{"rule":{"name":"terraform_unused_declarations","severity":"warning"},"message":"variable \"region\" is declared but not used","range":{"filename":"variables.tf","start":{"line":1}}},
+{"rule":{"name":"terraform_required_version","severity":"warning"},"message":"terraform \"required_version\" attribute is required","range":{"filename":"main.tf","start":{"line":1}}}
+],"errors":[]}`
+	baseReport := filepath.Join(dir, "tflint.json")
+	assert.NoError(t, os.WriteFile(baseReport, []byte(base), 0644))
+	os.Setenv("HEAD_COMMIT", "base-commit")
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{baseReport})
+	assert.NoError(t, err)
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("COMPARE_BASE", "true")
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "failure: tflint: 2 issues (1 error, 1 notice)", statuses[1])
+	assert.Len(t, bodies, 2)
+	assert.Contains(t, bodies[1], "**Compared to base `base-co`:** 2 new (1 error, 1 notice), 1 fixed (1 warning), 1 unchanged")
+	assert.NotContains(t, bodies[1], "terraform_unused_declarations")
+
+	// Without stored base results, all findings are shown
+	os.Setenv("BASE_COMMIT", "unknown-commit")
+	defer os.Unsetenv("BASE_COMMIT")
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, "failure: tflint: 3 issues (1 error, 1 warning, 1 notice)", statuses[2])
+	assert.Contains(t, bodies[2], "no results stored for `unknown`, showing all findings")
+}
@@ -0,0 +1,101 @@
+package cmd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecAndComment_EndToEnd(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	ctx := context.Background()
+	client := server.Client()
+	graphqlClient := server.GraphQLClient()
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+	os.Setenv("TEMPLATE_FILENAME", filepath.Join(tmpDir, "template.md"))
+
+	// A re-run hides the comment of the first run and posts its own; output saying "passed" succeeds
+	for _, command := range []string{"echo Hello passed", "echo World passed"} {
+		err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", command)
+		assert.NoError(t, err)
+		err = cmd.Comment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", command)
+		assert.NoError(t, err)
+	}
+
+	comments := server.Comments(123)
+	if assert.Len(t, comments, 2) {
+		assert.True(t, comments[0].IsMinimized)
+		assert.Contains(t, comments[0].Body, "Hello")
+		assert.Contains(t, comments[0].Body, "<!-- MINIMIZED -->")
+		assert.False(t, comments[1].IsMinimized)
+		assert.Contains(t, comments[1].Body, "## echo output")
+		assert.Contains(t, comments[1].Body, "World")
+	}
+
+	config.Init("echo")
+	statusContext := config.GetConfig().GHStatusContext
+	var states []string
+	for _, s := range server.Statuses("test-commit") {
+		if s.Context == statusContext {
+			states = append(states, s.State)
+		}
+	}
+	// Each run posts pending and success; linking the comment re-posts success
+	assert.Equal(t, []string{"pending", "success", "success", "pending", "success", "success"}, states)
+	latest := server.LatestStatuses("test-commit")[statusContext]
+	assert.Equal(t, "success", latest.State)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, comments[1].HTMLURL, latest.TargetURL)
+	}
+}
+
+func TestExecAndComment_EndToEndFailure(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	ctx := context.Background()
+	client := server.Client()
+	graphqlClient := server.GraphQLClient()
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+	os.Setenv("TEMPLATE_FILENAME", filepath.Join(tmpDir, "template.md"))
+
+	err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "ls /nonexistent-ghpc-path")
+	assert.NoError(t, err)
+	err = cmd.Comment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "ls /nonexistent-ghpc-path")
+	assert.NoError(t, err)
+
+	comments := server.VisibleComments(123)
+	if assert.Len(t, comments, 1) {
+		assert.Contains(t, comments[0].Body, "## ls output")
+		assert.Contains(t, comments[0].Body, "nonexistent-ghpc-path")
+	}
+
+	config.Init("ls")
+	latest := server.LatestStatuses("test-commit")[config.GetConfig().GHStatusContext]
+	assert.Equal(t, "failure", latest.State)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, comments[0].HTMLURL, latest.TargetURL)
+	}
+}
@@ -0,0 +1,35 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	for _, key := range []string{"HEAD_COMMIT", "BASE_REPO_OWNER", "BASE_REPO_NAME", "PULL_NUM", "PROJECT_NAME", "WORKSPACE", "PULL_URL", "STATUS_TARGET_URL", "GITHUB_TOKEN"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_REPOSITORY", "test-owner/test-repo")
+	t.Setenv("GITHUB_SHA", "test-commit")
+	t.Setenv("GITHUB_EVENT_PATH", "")
+	t.Setenv("GITHUB_REF", "refs/pull/123/merge")
+	t.Setenv("BASE_REPO_NAME", "other-repo")
+	t.Setenv("GITHUB_TOKEN", "secret-token")
+
+	var out bytes.Buffer
+	cmd.Env(&out)
+	assert.Contains(t, out.String(), "CI: GitHub Actions\n")
+	assert.Contains(t, out.String(), "HEAD_COMMIT=test-commit (GitHub Actions)\n")
+	assert.Contains(t, out.String(), "BASE_REPO_OWNER=test-owner (GitHub Actions)\n")
+	assert.Contains(t, out.String(), "BASE_REPO_NAME=other-repo (environment)\n")
+	assert.Contains(t, out.String(), "PULL_NUM=123 (GitHub Actions)\n")
+	assert.Contains(t, out.String(), "WORKSPACE= (not set)\n")
+	assert.Contains(t, out.String(), "GITHUB_TOKEN is set\n")
+	assert.NotContains(t, out.String(), "secret-token")
+}
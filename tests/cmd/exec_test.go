@@ -2,11 +2,16 @@ package cmd_test
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gh-pr-commenter/cmd"
 	"gh-pr-commenter/config"
+	"gh-pr-commenter/tests/fakegithub"
 	"github.com/google/go-github/v41/github"
 	"github.com/jarcoal/httpmock"
 	"github.com/machinebox/graphql"
@@ -58,3 +63,239 @@ func TestExecuteAndComment(t *testing.T) {
 	err = cmd.ExecuteAndComment(ctx, client, graphqlClient, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.PullNum, "echo Hello")
 	assert.NoError(t, err)
 }
+
+func TestExecuteAndComment_Timeout(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var states []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			states = append(states, status.GetState())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+	os.Setenv("COMMAND_TIMEOUT", "1s")
+	defer os.Unsetenv("COMMAND_TIMEOUT")
+
+	err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "sleep 30")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pending", "error"}, states)
+
+	output, err := os.ReadFile(tmpDir + "/.output-sleep.md")
+	assert.NoError(t, err)
+	assert.Contains(t, string(output), "Command timed out and was killed after 1s.")
+}
+
+func TestExecuteAndComment_SeparateStreams(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		httpmock.NewStringResponder(201, `{}`))
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+
+	err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "ls /nonexistent-ghpc-path")
+	assert.NoError(t, err)
+
+	combined, err := os.ReadFile(tmpDir + "/.output-ls.md")
+	assert.NoError(t, err)
+	assert.Contains(t, string(combined), "nonexistent-ghpc-path")
+
+	stderr, err := os.ReadFile(tmpDir + "/.output-ls.stderr.md")
+	assert.NoError(t, err)
+	assert.Contains(t, string(stderr), "nonexistent-ghpc-path")
+
+	stdout, err := os.ReadFile(tmpDir + "/.output-ls.stdout.md")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(stdout), "nonexistent-ghpc-path")
+}
+
+func TestExecuteAndComment_TerraformProfile(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var descriptions []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			descriptions = append(descriptions, status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+	os.Setenv("PROFILE", "terraform")
+	defer os.Unsetenv("PROFILE")
+
+	err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "cat ../pkg/terraform/testdata/plan.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pending: In Progress", "success: +2 ~1 -2"}, descriptions)
+
+	output, err := os.ReadFile(tmpDir + "/.output-cat.md")
+	assert.NoError(t, err)
+	assert.Contains(t, string(output), "#### Terraform plan: 2 to add, 1 to change, 2 to destroy")
+}
+
+func TestExecuteAndComment_RunFailures(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+
+	notExecutable := filepath.Join(tmpDir, "not-executable.sh")
+	assert.NoError(t, os.WriteFile(notExecutable, []byte("#!/bin/sh\necho hi\n"), 0644))
+	killsItself := filepath.Join(tmpDir, "kills-itself.sh")
+	assert.NoError(t, os.WriteFile(killsItself, []byte("#!/bin/sh\nkill -9 $$\n"), 0755))
+
+	tests := []struct {
+		command string
+		status  string
+		detail  string
+	}{
+		{"ghpc-missing-binary --version", "error: ghpc-missing-binary: command not found", "`ghpc-missing-binary` was not found on the PATH."},
+		{notExecutable, "error: " + notExecutable + ": permission denied", "permission denied"},
+		{killsItself, "error: " + killsItself + ": killed by a signal", "signal: killed"},
+	}
+	for _, tt := range tests {
+		statuses = nil
+		err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", tt.command)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"pending: In Progress", tt.status}, statuses, tt.command)
+
+		output, err := os.ReadFile(filepath.Join(tmpDir, ".output-"+filepath.Base(strings.Fields(tt.command)[0])+".md"))
+		if !assert.NoError(t, err, tt.command) {
+			continue
+		}
+		assert.Contains(t, string(output), "This is a problem with the environment, not a finding of the tool.")
+		assert.Contains(t, string(output), tt.detail)
+	}
+}
+
+func TestExecuteAndComment_ErrorAfterPending(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TMP_GHPC_DIR", t.TempDir())
+	os.Setenv("PROFILE", "unknown")
+	defer os.Unsetenv("PROFILE")
+
+	// The pending status must not be left behind when the run fails
+	err := cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "echo Hello")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"pending: In Progress", "error: echo: ghpc failed: unknown profile: unknown"}, statuses)
+}
+
+func TestExecuteAndComment_TerraformTimeoutFenced(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	t.Setenv("HEAD_COMMIT", "test-commit")
+	t.Setenv("BASE_REPO_OWNER", "test-owner")
+	t.Setenv("BASE_REPO_NAME", "test-repo")
+	t.Setenv("PULL_NUM", "123")
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("TMP_GHPC_DIR", tmpDir)
+	t.Setenv("TEMPLATE_FILENAME", filepath.Join(tmpDir, "template.md"))
+	t.Setenv("COMMAND_TIMEOUT", "1s")
+
+	// A terraform stub that is killed halfway through its plan, so the profile never renders it
+	bin := t.TempDir()
+	stub := "#!/bin/sh\necho '  # aws_instance.web will be created'\necho '  + ami = \"<computed>\"'\nsleep 30\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "terraform"), []byte(stub), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	err := cmd.ExecuteAndComment(ctx, server.Client(), server.GraphQLClient(), "test-owner", "test-repo", "123", "terraform plan")
+	assert.NoError(t, err)
+	err = cmd.Comment(ctx, server.Client(), server.GraphQLClient(), "test-owner", "test-repo", "123", "terraform plan")
+	assert.NoError(t, err)
+
+	comments := server.VisibleComments(123)
+	if assert.Len(t, comments, 1) {
+		body := comments[0].Body
+		assert.Contains(t, body, "> [!CAUTION]")
+		assert.Contains(t, body, "```diff\n  # aws_instance.web will be created\n  + ami = \"<computed>\"\n")
+		assert.Less(t, strings.Index(body, "> [!CAUTION]"), strings.Index(body, "```diff"))
+	}
+}
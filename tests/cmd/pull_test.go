@@ -0,0 +1,125 @@
+package cmd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/pkg/output"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupWithoutPullNum sets up the environment of a push-triggered run, which knows the commit but not the PR
+func setupWithoutPullNum(t *testing.T, env map[string]string) *config.Config {
+	t.Setenv("PULL_NUM", "")
+	os.Unsetenv("PULL_NUM")
+	t.Setenv("HEAD_COMMIT", "test-commit")
+	t.Setenv("BASE_REPO_OWNER", "test-owner")
+	t.Setenv("BASE_REPO_NAME", "test-repo")
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("TMP_GHPC_DIR", t.TempDir())
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	// Resolving the PR keeps it for later loads of the config
+	t.Cleanup(viper.Reset)
+	config.InitStatus("echo")
+	return config.GetConfig()
+}
+
+func TestResolvePullRequest_Commit(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+	server.SetPullRequestHead(1, "test-commit", "feature")
+	server.SetPullRequestHead(2, "test-commit", "feature-copy")
+	server.SetPullRequestHead(3, "test-commit", "old")
+	server.ClosePullRequest(3)
+	server.SetPullRequestHead(4, "other-commit", "other")
+
+	tests := []struct {
+		match    string
+		expected string
+		err      string
+	}{
+		{"", "2", ""},
+		{config.PullMatchNewest, "2", ""},
+		{config.PullMatchOldest, "1", ""},
+		{config.PullMatchError, "", "2 open pull requests match: [1 2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.match, func(t *testing.T) {
+			cnf := setupWithoutPullNum(t, map[string]string{"PULL_MATCH": tt.match})
+			err := cmd.ResolvePullRequest(context.Background(), server.Client(), cnf)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, config.GetConfig().PullNum)
+			assert.False(t, config.GetConfig().StatusOnly)
+
+			// The commands load the config again and keep the resolved PR
+			config.Init("echo")
+			assert.Equal(t, tt.expected, config.GetConfig().PullNum)
+		})
+	}
+}
+
+func TestResolvePullRequest_Branch(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+	// The PR has moved on since the commit was pushed
+	server.SetPullRequestHead(5, "newer-commit", "feature")
+
+	cnf := setupWithoutPullNum(t, map[string]string{"HEAD_BRANCH": "feature"})
+	err := cmd.ResolvePullRequest(context.Background(), server.Client(), cnf)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", config.GetConfig().PullNum)
+}
+
+func TestResolvePullRequest_Stacked(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+	server.SetPullRequestHead(6, "test-commit", "base")
+	// PR 7 is stacked on PR 6, so it contains the commit without being its head
+	server.SetPullRequestHead(7, "stacked-commit", "stacked")
+	server.AddPullRequestCommits(7, "test-commit")
+
+	cnf := setupWithoutPullNum(t, map[string]string{"PULL_MATCH": config.PullMatchError})
+	err := cmd.ResolvePullRequest(context.Background(), server.Client(), cnf)
+	assert.NoError(t, err)
+	assert.Equal(t, "6", config.GetConfig().PullNum)
+}
+
+func TestResolvePullRequest_StatusOnly(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	ctx := context.Background()
+	client := server.Client()
+	graphqlClient := server.GraphQLClient()
+
+	cnf := setupWithoutPullNum(t, map[string]string{"TEMPLATE_FILENAME": filepath.Join(t.TempDir(), "template.md")})
+	err := cmd.ResolvePullRequest(ctx, client, cnf)
+	assert.NoError(t, err)
+	assert.True(t, config.GetConfig().StatusOnly)
+	assert.Equal(t, "", config.GetConfig().PullNum)
+
+	err = cmd.ExecuteAndComment(ctx, client, graphqlClient, "test-owner", "test-repo", "", "echo passed")
+	assert.NoError(t, err)
+	err = cmd.Comment(ctx, client, graphqlClient, "test-owner", "test-repo", "", "echo passed")
+	assert.NoError(t, err)
+
+	assert.Empty(t, server.Comments(0))
+	latest := server.LatestStatuses("test-commit")[config.GetConfig().GHStatusContext]
+	assert.Equal(t, "success", latest.State)
+
+	// Nothing was posted, so the output isn't archived as posted
+	assert.FileExists(t, output.FileName(cnf.TmpGhpcDir, "echo"))
+	assert.NoFileExists(t, filepath.Join(cnf.TmpGhpcDir, ".output-echo.posted.md"))
+}
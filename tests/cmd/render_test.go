@@ -0,0 +1,96 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"github.com/google/go-github/v41/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender_Markdown(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, ".output-tflint.md")
+	assert.NoError(t, os.WriteFile(input, []byte("3 issue(s) found"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".output-tflint.stderr.md"), []byte("deprecated rule"), 0644))
+	template := filepath.Join(tmpDir, "template.md")
+	assert.NoError(t, os.WriteFile(template, []byte("{{ if .Stderr }}Warnings: {{ .Stderr }}\n{{ end }}---OUTPUT---"), 0644))
+
+	var out bytes.Buffer
+	err := cmd.Render(context.Background(), github.NewClient(nil), "", input, template, cmd.RenderMarkdown, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "## tflint output\nWarnings: deprecated rule\n3 issue(s) found <!-- Part #1 -->\n", out.String())
+}
+
+func TestRender_Parts(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "plan.txt")
+	line := strings.Repeat("x", 999) + "\n"
+	assert.NoError(t, os.WriteFile(input, []byte(strings.Repeat(line, 60)), 0644))
+
+	var out bytes.Buffer
+	err := cmd.Render(context.Background(), github.NewClient(nil), "terraform plan", input, filepath.Join(tmpDir, "missing.md"), cmd.RenderMarkdown, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "<!-- ghpc render: comment 1 of 2 -->\n## terraform output\n")
+	assert.Contains(t, out.String(), "<!-- ghpc render: comment 2 of 2 -->\n## terraform output\n")
+	assert.Contains(t, out.String(), "<!-- Part #2 -->")
+}
+
+func TestRender_HTML(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/markdown",
+		httpmock.NewStringResponder(200, "<h2>echo output</h2>\n<p>Hello</p>\n"))
+
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, ".output-echo.md")
+	assert.NoError(t, os.WriteFile(input, []byte("Hello"), 0644))
+
+	var out bytes.Buffer
+	err := cmd.Render(context.Background(), github.NewClient(nil), "", input, "", cmd.RenderHTML, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "<title>echo output</title>")
+	assert.Contains(t, out.String(), "<p>Hello</p>")
+}
+
+func TestRender_HTMLWithoutToken(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The Markdown API also answers unauthenticated requests
+	httpmock.RegisterResponder("POST", "https://api.github.com/markdown",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "" {
+				return httpmock.NewStringResponse(400, "unexpected Authorization header"), nil
+			}
+			return httpmock.NewStringResponse(200, "<p>Hello</p>\n"), nil
+		})
+
+	t.Setenv("GITHUB_TOKEN", "")
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, ".output-echo.md")
+	assert.NoError(t, os.WriteFile(input, []byte("Hello"), 0644))
+
+	var out bytes.Buffer
+	err := cmd.Render(context.Background(), github.NewClient(nil), "", input, "", cmd.RenderHTML, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "<p>Hello</p>")
+}
+
+func TestRender_UnknownCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "output.txt")
+	assert.NoError(t, os.WriteFile(input, []byte("Hello"), 0644))
+
+	err := cmd.Render(context.Background(), github.NewClient(nil), "", input, "", cmd.RenderMarkdown, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "pass it as an argument")
+}
@@ -0,0 +1,426 @@
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gh-pr-commenter/cmd"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/google/go-github/v41/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/machinebox/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+// setTempDirs keeps a test's output files and stored results in its own temporary directory
+func setTempDirs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMP_GHPC_DIR", dir)
+	t.Setenv("RESULT_STORE", filepath.Join(dir, "results"))
+}
+
+func TestReport_Tflint(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses, targetURLs []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetContext()+" "+status.GetState()+": "+status.GetDescription())
+			targetURLs = append(targetURLs, status.GetTargetURL())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+	var bodies []string
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		func(req *http.Request) (*http.Response, error) {
+			var comment github.IssueComment
+			if err := json.NewDecoder(req.Body).Decode(&comment); err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, comment.GetBody())
+			return httpmock.NewStringResponse(201, `{"html_url": "https://github.com/test-owner/test-repo/pull/123#issuecomment-1"}`), nil
+		})
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	setTempDirs(t)
+	os.Setenv("GH_STATUS_CONTEXT", "")
+	os.Setenv("FAIL_ON", "error")
+	defer os.Unsetenv("FAIL_ON")
+
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint*.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ghpc/tflint failure: tflint: 3 issues (1 error, 1 warning, 1 notice)"}, statuses)
+	assert.Equal(t, []string{"https://github.com/test-owner/test-repo/pull/123#issuecomment-1"}, targetURLs)
+	assert.Len(t, bodies, 1)
+	assert.Contains(t, bodies[0], "## tflint report")
+	assert.Contains(t, bodies[0], "aws_instance_invalid_type")
+
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"missing-*.json"})
+	assert.Error(t, err)
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "unknown", []string{"report.json"})
+	assert.Error(t, err)
+}
+
+func TestReport_TrivyFailOn(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(201, `{}`))
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	setTempDirs(t)
+	defer os.Unsetenv("FAIL_ON")
+
+	report := "../pkg/trivy/testdata/report.json"
+	os.Setenv("FAIL_ON", "CRITICAL,HIGH")
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "trivy", []string{report})
+	assert.NoError(t, err)
+
+	os.Setenv("FAIL_ON", "CRITICAL")
+	tmpDir := t.TempDir()
+	passing := tmpDir + "/passing.json"
+	err = os.WriteFile(passing, []byte(`{"SchemaVersion":2,"Results":[{"Target":"main.tf","Misconfigurations":[{"ID":"AVD-AWS-0086","Severity":"HIGH","Status":"FAIL"}]}]}`), 0644)
+	assert.NoError(t, err)
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "trivy", []string{passing})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"failure: trivy: 3 issues (1 critical, 1 high, 1 low)",
+		"success: trivy: 1 issue (1 high)",
+	}, statuses)
+}
+
+func TestReport_SARIFAnnotations(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		httpmock.NewStringResponder(201, `{}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	var body string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		func(req *http.Request) (*http.Response, error) {
+			var comment github.IssueComment
+			if err := json.NewDecoder(req.Body).Decode(&comment); err != nil {
+				return nil, err
+			}
+			body = comment.GetBody()
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+	var checkRun github.CreateCheckRunOptions
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/check-runs",
+		func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&checkRun); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(201, `{"id": 1}`), nil
+		})
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	setTempDirs(t)
+	os.Setenv("GH_STATUS_CONTEXT", "")
+	os.Setenv("ANNOTATIONS", "true")
+	defer os.Unsetenv("ANNOTATIONS")
+
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "sarif", []string{"../pkg/findings/testdata/checkov.sarif"})
+	assert.NoError(t, err)
+	assert.Contains(t, body, "#### checkov, gosec: 3 issues (1 high, 1 warning, 1 note)")
+
+	assert.Equal(t, "ghpc/sarif", checkRun.Name)
+	assert.Equal(t, "failure", checkRun.GetConclusion())
+	// The gosec finding has no location and is left out
+	assert.Len(t, checkRun.Output.Annotations, 2)
+	assert.Equal(t, "modules/s3/main.tf", checkRun.Output.Annotations[0].GetPath())
+	assert.Equal(t, "failure", checkRun.Output.Annotations[0].GetAnnotationLevel())
+	assert.Equal(t, "notice", checkRun.Output.Annotations[1].GetAnnotationLevel())
+}
+
+func TestReport_AnnotationsOversizedSummary(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	t.Setenv("HEAD_COMMIT", "test-commit")
+	t.Setenv("BASE_REPO_OWNER", "test-owner")
+	t.Setenv("BASE_REPO_NAME", "test-repo")
+	t.Setenv("PULL_NUM", "123")
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("GH_STATUS_CONTEXT", "")
+	setTempDirs(t)
+	t.Setenv("ANNOTATIONS", "true")
+
+	// The rendered report is far longer than the 65535 characters the checks API accepts
+	var issues []string
+	for i := 1; i <= 1000; i++ {
+		issues = append(issues, fmt.Sprintf(`{"rule":{"name":"aws_instance_invalid_type","severity":"error"},"message":"\"t1.2xlarge\" is an invalid value as instance_type of instance %d","range":{"filename":"main.tf","start":{"line":%d}}}`, i, i))
+	}
+	report := filepath.Join(t.TempDir(), "tflint.json")
+	assert.NoError(t, os.WriteFile(report, []byte(`{"issues":[`+strings.Join(issues, ",")+`],"errors":[]}`), 0644))
+
+	err := cmd.Report(context.Background(), server.Client(), server.GraphQLClient(), "test-owner", "test-repo", "123", "tflint", []string{report})
+	assert.NoError(t, err)
+
+	comments := server.Comments(123)
+	runs := server.CheckRuns("test-commit")
+	if assert.NotEmpty(t, comments) && assert.Len(t, runs, 1) {
+		assert.LessOrEqual(t, len(runs[0].Summary), 65535)
+		assert.Contains(t, runs[0].Summary, "was truncated. See the [pull request comment]("+comments[0].HTMLURL+")")
+		assert.Len(t, runs[0].Annotations, 1000)
+	}
+}
+
+func TestReport_JUnit(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetContext()+" "+status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(201, `{}`))
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	setTempDirs(t)
+	os.Setenv("GH_STATUS_CONTEXT", "")
+
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "junit", []string{"../pkg/junit/testdata/*.xml"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ghpc/junit failure: junit: 2 passed, 2 failed, 1 skipped"}, statuses)
+}
+
+func TestReport_Review(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		httpmock.NewStringResponder(201, `{}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/pulls/123/files",
+		httpmock.NewStringResponder(200, `[{"filename": "main.tf", "patch": "@@ -10,3 +10,4 @@\n resource \"aws_instance\" \"web\" {\n+  instance_type = \"t1.2xlarge\"\n   ami = \"ami-1\"\n }"}]`))
+	var graphqlQueries []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/graphql",
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Query string
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			graphqlQueries = append(graphqlQueries, body.Query)
+			if strings.Contains(body.Query, "resolveReviewThread") {
+				return httpmock.NewStringResponse(200, `{"data": {"resolveReviewThread": {"thread": {"isResolved": true}}}}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"data": {"repository": {"pullRequest": {"reviewThreads": {"nodes": [
+				{"id": "old", "isResolved": false, "comments": {"nodes": [{"body": "old finding\n\n<!-- ghpc-review: ghpc/tflint -->"}]}},
+				{"id": "human", "isResolved": false, "comments": {"nodes": [{"body": "please rename this"}]}}
+			]}}}}}`), nil
+		})
+	var review github.PullRequestReviewRequest
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/pulls/123/reviews",
+		func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&review); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+	var bodies []string
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		func(req *http.Request) (*http.Response, error) {
+			var comment github.IssueComment
+			if err := json.NewDecoder(req.Body).Decode(&comment); err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, comment.GetBody())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	setTempDirs(t)
+	os.Setenv("GH_STATUS_CONTEXT", "")
+	os.Setenv("REVIEW_COMMENTS", "true")
+	defer os.Unsetenv("REVIEW_COMMENTS")
+
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+
+	// Only the previous ghpc thread is resolved
+	assert.Len(t, graphqlQueries, 2)
+	assert.Contains(t, graphqlQueries[1], "resolveReviewThread")
+
+	// The finding on the added line is posted inline
+	assert.Equal(t, "COMMENT", review.GetEvent())
+	assert.Len(t, review.Comments, 1)
+	assert.Equal(t, "main.tf", review.Comments[0].GetPath())
+	assert.Equal(t, 12, review.Comments[0].GetLine())
+	assert.Contains(t, review.Comments[0].GetBody(), "aws_instance_invalid_type")
+	assert.Contains(t, review.Comments[0].GetBody(), "<!-- ghpc-review: ghpc/tflint -->")
+
+	// The findings outside the diff stay in the summary comment
+	assert.Len(t, bodies, 1)
+	assert.NotContains(t, bodies[0], "aws_instance_invalid_type")
+	assert.Contains(t, bodies[0], "terraform_unused_declarations")
+	assert.Contains(t, bodies[0], "1 findings on changed lines were posted as review comments")
+}
+
+func TestReport_OnlyChanged(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	var statuses []string
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var status github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status.GetState()+": "+status.GetDescription())
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/pulls/123/files",
+		httpmock.NewStringResponder(200, `[{"filename": "main.tf", "patch": "@@ -3,3 +3,3 @@\n   count = 1\n-  ami = \"${var.ami}\"\n+  ami = \"${var.ami_id}\"\n   tags = {}"}]`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(201, `{}`))
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	setTempDirs(t)
+	os.Setenv("GH_STATUS_CONTEXT", "")
+	os.Setenv("FAIL_ON", "error")
+	defer os.Unsetenv("FAIL_ON")
+	defer os.Unsetenv("ONLY_CHANGED")
+
+	os.Setenv("ONLY_CHANGED", "files")
+	err := cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+
+	// Only the notice on line 4 is on a changed line, so the error on line 12 no longer fails the status
+	os.Setenv("ONLY_CHANGED", "lines")
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"failure: tflint: 2 issues (1 error, 1 notice)",
+		"success: tflint: 1 issue (1 notice)",
+	}, statuses)
+
+	os.Setenv("ONLY_CHANGED", "hunks")
+	err = cmd.Report(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.Error(t, err)
+}
+
+func TestReport_OnlyChangedSubdirectory(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+	// The project lives in modules/app, while tflint --chdir reports paths relative to it
+	server.SetFiles(123, []*github.CommitFile{
+		{Filename: github.String("modules/app/main.tf"), Patch: github.String("@@ -3,3 +3,3 @@\n   count = 1\n-  ami = \"${var.ami}\"\n+  ami = \"${var.ami_id}\"\n   tags = {}")},
+		{Filename: github.String("variables.tf"), Patch: github.String("@@ -1,3 +1,3 @@\n-variable \"old\" {}\n+variable \"region\" {}\n \n ")},
+	})
+
+	t.Setenv("HEAD_COMMIT", "test-commit")
+	t.Setenv("BASE_REPO_OWNER", "test-owner")
+	t.Setenv("BASE_REPO_NAME", "test-repo")
+	t.Setenv("PULL_NUM", "123")
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("GH_STATUS_CONTEXT", "")
+	setTempDirs(t)
+	t.Setenv("REPO_REL_DIR", "modules/app")
+	t.Setenv("ONLY_CHANGED", "lines")
+	t.Setenv("REVIEW_COMMENTS", "true")
+
+	err := cmd.Report(context.Background(), server.Client(), server.GraphQLClient(), "test-owner", "test-repo", "123", "tflint", []string{"../pkg/findings/testdata/tflint.json"})
+	assert.NoError(t, err)
+
+	// variables.tf at the repository root isn't the project's variables.tf
+	latest := server.LatestStatuses("test-commit")["ghpc/tflint"]
+	assert.Equal(t, "tflint: 1 issue (1 notice)", latest.Description)
+	threads := server.ReviewThreads(123)
+	if assert.Len(t, threads, 1) {
+		assert.Equal(t, "modules/app/main.tf", threads[0].Path)
+		assert.Equal(t, 4, threads[0].Line)
+	}
+}
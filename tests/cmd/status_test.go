@@ -0,0 +1,149 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"gh-pr-commenter/cmd"
+	"gh-pr-commenter/config"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/google/go-github/v41/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetStatus(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var posted []github.RepoStatus
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var s github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+				return nil, err
+			}
+			posted = append(posted, s)
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	err := cmd.SetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "success", "ci/deploy", "Skipped: no changes", "https://example.com/run/1")
+	assert.NoError(t, err)
+	err = cmd.SetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "pending", "ci/deploy", "", "")
+	assert.NoError(t, err)
+
+	assert.Len(t, posted, 2)
+	assert.Equal(t, "Skipped: no changes", posted[0].GetDescription())
+	assert.Equal(t, "https://example.com/run/1", posted[0].GetTargetURL())
+	assert.Equal(t, "ci/deploy", posted[1].GetContext())
+	assert.Equal(t, "In Progress", posted[1].GetDescription())
+
+	assert.Error(t, cmd.SetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "skipped", "ci/deploy", "", ""))
+	assert.Error(t, cmd.SetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "success", "", "", ""))
+	assert.Len(t, posted, 2)
+}
+
+func TestGetStatus(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/commits/test-commit/status",
+		httpmock.NewStringResponder(200, `{"state": "failure", "total_count": 2, "statuses": [
+			{"context": "ghpc/tflint", "state": "failure", "description": "tflint: 1 issue (1 error)"},
+			{"context": "ghpc/trivy", "state": "success", "description": "trivy: no issues"}
+		]}`))
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+
+	var out bytes.Buffer
+	assert.NoError(t, cmd.GetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "", &out))
+	assert.Equal(t, "failure (2 contexts)\nfailure  ghpc/tflint: tflint: 1 issue (1 error)\nsuccess  ghpc/trivy: trivy: no issues\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, cmd.GetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "ghpc/trivy", &out))
+	assert.Equal(t, "success\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, cmd.GetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "ghpc/checkov", &out))
+	assert.Equal(t, "missing\n", out.String())
+}
+
+func TestReconcileStatus(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	recent := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	stale := time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339)
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/commits/test-commit/status",
+		httpmock.NewStringResponder(200, `{"state": "pending", "total_count": 4, "statuses": [
+			{"context": "ghpc/tflint", "state": "pending", "updated_at": "`+stale+`"},
+			{"context": "ghpc/trivy", "state": "pending", "updated_at": "`+recent+`"},
+			{"context": "ghpc/checkov", "state": "success", "updated_at": "`+stale+`"},
+			{"context": "ci/build", "state": "pending", "updated_at": "`+stale+`"}
+		]}`))
+	var posted []github.RepoStatus
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var s github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+				return nil, err
+			}
+			posted = append(posted, s)
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+
+	reconciled, err := cmd.ReconcileStatus(ctx, client, "test-owner", "test-repo", "test-commit", "ghpc/", time.Hour, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ghpc/tflint"}, reconciled)
+	assert.Len(t, posted, 1)
+	assert.Equal(t, "error", posted[0].GetState())
+	assert.Contains(t, posted[0].GetDescription(), "Stale: the run stopped without a result")
+
+	reconciled, err = cmd.ReconcileStatus(ctx, client, "test-owner", "test-repo", "test-commit", "ghpc/", 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ghpc/tflint", "ghpc/trivy"}, reconciled)
+}
+
+func TestGetStatus_StdoutOnlyState(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("GITHUB_TOKEN", "secret-test-token")
+	defer os.Unsetenv("GITHUB_TOKEN")
+
+	ctx := context.Background()
+	client := server.Client()
+	assert.NoError(t, cmd.SetStatus(ctx, client, "test-owner", "test-repo", "test-commit", "success", "ghpc/trivy", "", ""))
+
+	// state=$(ghpc status get ...) captures stdout, so the settings must not be printed there
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stdout = w
+	config.InitStatus("status")
+	cnf := config.GetConfig()
+	err = cmd.GetStatus(ctx, client, cnf.BaseRepoOwner, cnf.BaseRepoName, cnf.HeadCommit, "ghpc/trivy", os.Stdout)
+	w.Close()
+	os.Stdout = stdout
+	assert.NoError(t, err)
+
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "success\n", string(out))
+	assert.NotContains(t, string(out), "secret-test-token")
+}
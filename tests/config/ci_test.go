@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCI_None(t *testing.T) {
+	os.Clearenv()
+	assert.Nil(t, config.DetectCI())
+}
+
+func TestDetectCI_Atlantis(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("ATLANTIS_TERRAFORM_VERSION", "1.7.0")
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("PULL_NUM", "123")
+
+	ci := config.DetectCI()
+	if assert.NotNil(t, ci) {
+		assert.Equal(t, "Atlantis", ci.Name)
+		assert.Equal(t, map[string]string{"HEAD_COMMIT": "test-commit", "PULL_NUM": "123"}, ci.Settings)
+	}
+}
+
+func TestDetectCI_GitHubActionsPullRequest(t *testing.T) {
+	os.Clearenv()
+	event := filepath.Join(t.TempDir(), "event.json")
+	err := os.WriteFile(event, []byte(`{"pull_request": {"number": 123, "html_url": "https://github.com/test-owner/test-repo/pull/123", "head": {"sha": "head-commit"}}}`), 0644)
+	assert.NoError(t, err)
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_REPOSITORY", "test-owner/test-repo")
+	os.Setenv("GITHUB_SHA", "merge-commit")
+	os.Setenv("GITHUB_EVENT_PATH", event)
+
+	ci := config.DetectCI()
+	if assert.NotNil(t, ci) {
+		assert.Equal(t, "GitHub Actions", ci.Name)
+		assert.Equal(t, map[string]string{
+			"HEAD_COMMIT":     "head-commit",
+			"BASE_REPO_OWNER": "test-owner",
+			"BASE_REPO_NAME":  "test-repo",
+			"PULL_NUM":        "123",
+			"PULL_URL":        "https://github.com/test-owner/test-repo/pull/123",
+		}, ci.Settings)
+	}
+}
+
+func TestDetectCI_GitHubActionsPush(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_REPOSITORY", "test-owner/test-repo")
+	os.Setenv("GITHUB_SHA", "push-commit")
+	os.Setenv("GITHUB_REF", "refs/heads/main")
+
+	ci := config.DetectCI()
+	if assert.NotNil(t, ci) {
+		assert.Equal(t, "push-commit", ci.Settings["HEAD_COMMIT"])
+		assert.NotContains(t, ci.Settings, "PULL_NUM")
+	}
+}
+
+func TestDetectCI_Buildkite(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("BUILDKITE", "true")
+	os.Setenv("BUILDKITE_COMMIT", "test-commit")
+	os.Setenv("BUILDKITE_REPO", "git@github.com:test-owner/test-repo.git")
+	os.Setenv("BUILDKITE_PULL_REQUEST", "false")
+	os.Setenv("BUILDKITE_BUILD_URL", "https://buildkite.com/acme/pipeline/builds/7")
+
+	ci := config.DetectCI()
+	if assert.NotNil(t, ci) {
+		assert.Equal(t, "Buildkite", ci.Name)
+		assert.Equal(t, map[string]string{
+			"HEAD_COMMIT":       "test-commit",
+			"BASE_REPO_OWNER":   "test-owner",
+			"BASE_REPO_NAME":    "test-repo",
+			"STATUS_TARGET_URL": "https://buildkite.com/acme/pipeline/builds/7",
+		}, ci.Settings)
+	}
+}
+
+func TestDetectCI_Jenkins(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("JENKINS_URL", "https://jenkins.example.com/")
+	os.Setenv("GIT_COMMIT", "test-commit")
+	os.Setenv("GIT_URL", "https://github.com/other-owner/other-repo.git")
+	os.Setenv("CHANGE_ID", "123")
+	os.Setenv("CHANGE_URL", "https://github.com/test-owner/test-repo/pull/123")
+
+	ci := config.DetectCI()
+	if assert.NotNil(t, ci) {
+		assert.Equal(t, "Jenkins", ci.Name)
+		assert.Equal(t, "test-owner", ci.Settings["BASE_REPO_OWNER"])
+		assert.Equal(t, "test-repo", ci.Settings["BASE_REPO_NAME"])
+		assert.Equal(t, "123", ci.Settings["PULL_NUM"])
+		assert.Equal(t, "test-commit", ci.Settings["HEAD_COMMIT"])
+	}
+}
+
+func TestInit_FromCI(t *testing.T) {
+	os.Clearenv()
+	viper.Reset()
+	os.Setenv("BUILDKITE", "true")
+	os.Setenv("BUILDKITE_COMMIT", "test-commit")
+	os.Setenv("BUILDKITE_REPO", "https://github.com/test-owner/test-repo.git")
+	os.Setenv("BUILDKITE_PULL_REQUEST", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	// Variables set explicitly take precedence
+	os.Setenv("PULL_NUM", "456")
+
+	config.Init("test-cmd")
+	cnf := config.GetConfig()
+	assert.Equal(t, "Buildkite", cnf.CI)
+	assert.Equal(t, "test-commit", cnf.HeadCommit)
+	assert.Equal(t, "test-owner", cnf.BaseRepoOwner)
+	assert.Equal(t, "test-repo", cnf.BaseRepoName)
+	assert.Equal(t, "456", cnf.PullNum)
+	viper.Reset()
+}
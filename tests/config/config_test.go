@@ -37,6 +37,7 @@ func TestInit_WithDefaults(t *testing.T) {
 	assert.Equal(t, config.DefaultWorkspace, cnf.Workspace)
 	assert.Equal(t, config.DefaultTemplateFile, cnf.TemplateFilename)
 	assert.Equal(t, config.DefaultTmpGhpcDir, cnf.TmpGhpcDir)
+	assert.Equal(t, "ghpc/destroy-guard: "+config.DefaultProjectName, cnf.DestroyGuardContext)
 }
 
 func TestInit_WithEnvVariables(t *testing.T) {
@@ -69,6 +70,7 @@ func TestInit_WithEnvVariables(t *testing.T) {
 	assert.Equal(t, "test-commit", cnf.HeadCommit)
 	assert.Equal(t, "test-project", cnf.ProjectName)
 	assert.Equal(t, "test-context/test-cmd: test-project", cnf.GHStatusContext)
+	assert.Equal(t, "test-context/destroy-guard: test-project", cnf.DestroyGuardContext)
 	assert.Equal(t, "test-workspace", cnf.Workspace)
 	assert.Equal(t, "test-owner", cnf.BaseRepoOwner)
 	assert.Equal(t, "test-repo", cnf.BaseRepoName)
@@ -107,3 +109,43 @@ func TestValidateConfig_MissingKeys(t *testing.T) {
 	config.Init("test-cmd")
 	config.ValidateConfig()
 }
+
+func TestInit_StatusTargetURL(t *testing.T) {
+	os.Clearenv()
+	viper.Reset()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+
+	// Atlantis only knows the PR
+	os.Setenv("PULL_URL", "https://github.com/test-owner/test-repo/pull/123")
+	config.Init("test-cmd")
+	assert.Equal(t, "https://github.com/test-owner/test-repo/pull/123", config.GetConfig().StatusTargetURL)
+
+	// GitHub Actions links the workflow run
+	os.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	os.Setenv("GITHUB_REPOSITORY", "test-owner/test-repo")
+	os.Setenv("GITHUB_RUN_ID", "42")
+	config.Init("test-cmd")
+	assert.Equal(t, "https://github.com/test-owner/test-repo/actions/runs/42", config.GetConfig().StatusTargetURL)
+
+	os.Setenv("STATUS_TARGET_URL", "https://ci.example.com/build/7")
+	config.Init("test-cmd")
+	assert.Equal(t, "https://ci.example.com/build/7", config.GetConfig().StatusTargetURL)
+}
+
+func TestInitStatus_WithoutPullNum(t *testing.T) {
+	os.Clearenv()
+	viper.Reset()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+
+	config.InitStatus("status")
+	cnf := config.GetConfig()
+	assert.Equal(t, "test-commit", cnf.HeadCommit)
+	assert.Equal(t, "", cnf.PullNum)
+}
@@ -0,0 +1,731 @@
+// Package fakegithub is an in-memory stand-in for the parts of the GitHub REST and GraphQL APIs ghpc uses,
+// so tests can run commands end to end and assert on the resulting pull request state.
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+)
+
+// Comment is an issue comment as the fake server stores it
+type Comment struct {
+	ID              int64
+	NodeID          string
+	Number          int
+	Body            string
+	HTMLURL         string
+	IsMinimized     bool
+	MinimizedReason string
+}
+
+// Status is a commit status as the fake server stores it
+type Status struct {
+	ID          int64
+	SHA         string
+	State       string
+	Context     string
+	Description string
+	TargetURL   string
+	CreatedAt   time.Time
+}
+
+// CheckRun is a check run as the fake server stores it, with its annotations accumulated over updates
+type CheckRun struct {
+	ID          int64
+	Name        string
+	HeadSHA     string
+	Status      string
+	Conclusion  string
+	Title       string
+	Summary     string
+	Annotations []*github.CheckRunAnnotation
+}
+
+// ReviewThread is a thread started by a review comment
+type ReviewThread struct {
+	NodeID     string
+	Number     int
+	Path       string
+	Line       int
+	Body       string
+	IsResolved bool
+}
+
+// Server is a fake GitHub API for a single repository
+type Server struct {
+	Owner string
+	Repo  string
+
+	mu        sync.Mutex
+	server    *httptest.Server
+	nextID    int64
+	comments  []*Comment
+	statuses  []*Status
+	checkRuns []*CheckRun
+	threads   []*ReviewThread
+	files     map[int][]*github.CommitFile
+	pulls     map[int]*github.PullRequest
+	commits   map[int][]string
+	labels    map[int][]string
+}
+
+// firstPattern matches the page size of the review threads a query selects
+var firstPattern = regexp.MustCompile(`reviewThreads\(first: (\d+)`)
+
+// routes maps REST endpoints to their handlers; patterns match the path below /repos/{owner}/{repo}
+var routes = []struct {
+	method  string
+	pattern *regexp.Regexp
+	handle  func(s *Server, w http.ResponseWriter, r *http.Request, args []string)
+}{
+	{http.MethodGet, regexp.MustCompile(`^/issues/(\d+)/comments$`), (*Server).listComments},
+	{http.MethodPost, regexp.MustCompile(`^/issues/(\d+)/comments$`), (*Server).createComment},
+	{http.MethodPatch, regexp.MustCompile(`^/issues/comments/(\d+)$`), (*Server).editComment},
+	{http.MethodGet, regexp.MustCompile(`^/issues/(\d+)/labels$`), (*Server).listLabels},
+	{http.MethodPost, regexp.MustCompile(`^/statuses/([^/]+)$`), (*Server).createStatus},
+	{http.MethodGet, regexp.MustCompile(`^/commits/([^/]+)/statuses$`), (*Server).listStatuses},
+	{http.MethodGet, regexp.MustCompile(`^/commits/([^/]+)/status$`), (*Server).combinedStatus},
+	{http.MethodPost, regexp.MustCompile(`^/check-runs$`), (*Server).createCheckRun},
+	{http.MethodPatch, regexp.MustCompile(`^/check-runs/(\d+)$`), (*Server).updateCheckRun},
+	{http.MethodGet, regexp.MustCompile(`^/commits/([^/]+)/pulls$`), (*Server).listPullsWithCommit},
+	{http.MethodGet, regexp.MustCompile(`^/pulls$`), (*Server).listPulls},
+	{http.MethodGet, regexp.MustCompile(`^/pulls/(\d+)$`), (*Server).getPull},
+	{http.MethodGet, regexp.MustCompile(`^/pulls/(\d+)/files$`), (*Server).listFiles},
+	{http.MethodPost, regexp.MustCompile(`^/pulls/(\d+)/reviews$`), (*Server).createReview},
+}
+
+// New starts a fake GitHub server for owner/repo. Close it when done.
+func New(owner, repo string) *Server {
+	s := &Server{
+		Owner:   owner,
+		Repo:    repo,
+		files:   map[int][]*github.CommitFile{},
+		pulls:   map[int]*github.PullRequest{},
+		commits: map[int][]string{},
+		labels:  map[int][]string{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL is the server's base URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns a REST client that talks to the server
+func (s *Server) Client() *github.Client {
+	client := github.NewClient(s.server.Client())
+	client.BaseURL, _ = client.BaseURL.Parse(s.server.URL + "/")
+	return client
+}
+
+// GraphQLClient returns a GraphQL client that talks to the server
+func (s *Server) GraphQLClient() *graphql.Client {
+	return graphql.NewClient(s.server.URL+"/graphql", graphql.WithHTTPClient(s.server.Client()))
+}
+
+// SetPullRequest sets the base commit of pull request number
+func (s *Server) SetPullRequest(number int, baseSHA string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pull(number).Base = &github.PullRequestBranch{SHA: github.String(baseSHA)}
+}
+
+// SetPullRequestHead sets the head commit and branch of pull request number
+func (s *Server) SetPullRequestHead(number int, headSHA, branch string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pull(number).Head = &github.PullRequestBranch{SHA: github.String(headSHA), Ref: github.String(branch), Label: github.String(s.Owner + ":" + branch)}
+}
+
+// AddPullRequestCommits adds commits to pull request number below its head, e.g. those of the PR it's stacked on
+func (s *Server) AddPullRequestCommits(number int, shas ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pull(number)
+	s.commits[number] = append(s.commits[number], shas...)
+}
+
+// ClosePullRequest closes pull request number
+func (s *Server) ClosePullRequest(number int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pull(number).State = github.String("closed")
+}
+
+// pull returns pull request number, opening it if it doesn't exist yet
+func (s *Server) pull(number int) *github.PullRequest {
+	pull, ok := s.pulls[number]
+	if !ok {
+		pull = &github.PullRequest{Number: github.Int(number), State: github.String("open")}
+		s.pulls[number] = pull
+	}
+	return pull
+}
+
+// SetFiles sets the files changed by pull request number
+func (s *Server) SetFiles(number int, files []*github.CommitFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[number] = files
+}
+
+// SetLabels sets the labels of pull request number
+func (s *Server) SetLabels(number int, labels ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labels[number] = labels
+}
+
+// Comments returns copies of the comments on pull request number, oldest first
+func (s *Server) Comments(number int) []Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var comments []Comment
+	for _, c := range s.comments {
+		if c.Number == number {
+			comments = append(comments, *c)
+		}
+	}
+	return comments
+}
+
+// VisibleComments returns copies of the comments on pull request number that aren't minimized, oldest first
+func (s *Server) VisibleComments(number int) []Comment {
+	var visible []Comment
+	for _, c := range s.Comments(number) {
+		if !c.IsMinimized {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
+
+// Statuses returns copies of every status posted on sha, oldest first
+func (s *Server) Statuses(sha string) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var statuses []Status
+	for _, st := range s.statuses {
+		if st.SHA == sha {
+			statuses = append(statuses, *st)
+		}
+	}
+	return statuses
+}
+
+// LatestStatuses returns the latest status of each context on sha
+func (s *Server) LatestStatuses(sha string) map[string]Status {
+	latest := map[string]Status{}
+	for _, st := range s.Statuses(sha) {
+		latest[st.Context] = st
+	}
+	return latest
+}
+
+// CheckRuns returns copies of the check runs on sha
+func (s *Server) CheckRuns(sha string) []CheckRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []CheckRun
+	for _, run := range s.checkRuns {
+		if run.HeadSHA == sha {
+			runs = append(runs, *run)
+		}
+	}
+	return runs
+}
+
+// ReviewThreads returns copies of the review threads on pull request number
+func (s *Server) ReviewThreads(number int) []ReviewThread {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var threads []ReviewThread
+	for _, t := range s.threads {
+		if t.Number == number {
+			threads = append(threads, *t)
+		}
+	}
+	return threads
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/graphql" && r.Method == http.MethodPost {
+		s.graphql(w, r)
+		return
+	}
+	prefix := fmt.Sprintf("/repos/%s/%s", s.Owner, s.Repo)
+	if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	for _, route := range routes {
+		if route.method != r.Method {
+			continue
+		}
+		if args := route.pattern.FindStringSubmatch(path); args != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			route.handle(s, w, r, args[1:])
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+func (s *Server) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) listComments(w http.ResponseWriter, r *http.Request, args []string) {
+	number, _ := strconv.Atoi(args[0])
+	comments := []*github.IssueComment{}
+	for _, c := range s.comments {
+		if c.Number == number {
+			comments = append(comments, c.issueComment())
+		}
+	}
+	writeJSON(w, http.StatusOK, comments)
+}
+
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request, args []string) {
+	number, _ := strconv.Atoi(args[0])
+	var req github.IssueComment
+	if !readJSON(w, r, &req) {
+		return
+	}
+	id := s.id()
+	c := &Comment{
+		ID:      id,
+		NodeID:  fmt.Sprintf("IC_%d", id),
+		Number:  number,
+		Body:    req.GetBody(),
+		HTMLURL: fmt.Sprintf("https://github.com/%s/%s/pull/%d#issuecomment-%d", s.Owner, s.Repo, number, id),
+	}
+	s.comments = append(s.comments, c)
+	writeJSON(w, http.StatusCreated, c.issueComment())
+}
+
+func (s *Server) editComment(w http.ResponseWriter, r *http.Request, args []string) {
+	id, _ := strconv.ParseInt(args[0], 10, 64)
+	var req github.IssueComment
+	if !readJSON(w, r, &req) {
+		return
+	}
+	for _, c := range s.comments {
+		if c.ID == id {
+			c.Body = req.GetBody()
+			writeJSON(w, http.StatusOK, c.issueComment())
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+func (s *Server) listLabels(w http.ResponseWriter, r *http.Request, args []string) {
+	number, _ := strconv.Atoi(args[0])
+	labels := []*github.Label{}
+	for _, name := range s.labels[number] {
+		labels = append(labels, &github.Label{Name: github.String(name)})
+	}
+	writeJSON(w, http.StatusOK, labels)
+}
+
+func (s *Server) createStatus(w http.ResponseWriter, r *http.Request, args []string) {
+	var req github.RepoStatus
+	if !readJSON(w, r, &req) {
+		return
+	}
+	switch req.GetState() {
+	case "error", "failure", "pending", "success":
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed: state")
+		return
+	}
+	if len([]rune(req.GetDescription())) > 140 {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed: description is too long")
+		return
+	}
+	context := req.GetContext()
+	if context == "" {
+		context = "default"
+	}
+	st := &Status{
+		ID:          s.id(),
+		SHA:         args[0],
+		State:       req.GetState(),
+		Context:     context,
+		Description: req.GetDescription(),
+		TargetURL:   req.GetTargetURL(),
+		// Statuses posted in quick succession must still list in order
+		CreatedAt: time.Now().Add(time.Duration(s.nextID) * time.Millisecond),
+	}
+	s.statuses = append(s.statuses, st)
+	writeJSON(w, http.StatusCreated, st.repoStatus())
+}
+
+func (s *Server) listStatuses(w http.ResponseWriter, r *http.Request, args []string) {
+	// GitHub lists statuses newest first
+	statuses := []*github.RepoStatus{}
+	for i := len(s.statuses) - 1; i >= 0; i-- {
+		if s.statuses[i].SHA == args[0] {
+			statuses = append(statuses, s.statuses[i].repoStatus())
+		}
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) combinedStatus(w http.ResponseWriter, r *http.Request, args []string) {
+	latest := map[string]*Status{}
+	for _, st := range s.statuses {
+		if st.SHA == args[0] {
+			latest[st.Context] = st
+		}
+	}
+	contexts := make([]string, 0, len(latest))
+	for context := range latest {
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+	combined := &github.CombinedStatus{SHA: github.String(args[0]), Statuses: []*github.RepoStatus{}}
+	state := "success"
+	for _, context := range contexts {
+		st := latest[context]
+		combined.Statuses = append(combined.Statuses, st.repoStatus())
+		switch {
+		case st.State == "error" || st.State == "failure":
+			state = "failure"
+		case st.State == "pending" && state == "success":
+			state = "pending"
+		}
+	}
+	if len(contexts) == 0 {
+		state = "pending"
+	}
+	combined.State = github.String(state)
+	combined.TotalCount = github.Int(len(contexts))
+	writeJSON(w, http.StatusOK, combined)
+}
+
+func (s *Server) createCheckRun(w http.ResponseWriter, r *http.Request, args []string) {
+	var req github.CreateCheckRunOptions
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if !validCheckRunOutput(w, req.Output) {
+		return
+	}
+	run := &CheckRun{
+		ID:      s.id(),
+		Name:    req.Name,
+		HeadSHA: req.HeadSHA,
+		Status:  req.GetStatus(),
+	}
+	if req.Conclusion != nil {
+		run.Conclusion = *req.Conclusion
+	}
+	run.applyOutput(req.Output)
+	s.checkRuns = append(s.checkRuns, run)
+	writeJSON(w, http.StatusCreated, run.checkRun())
+}
+
+func (s *Server) updateCheckRun(w http.ResponseWriter, r *http.Request, args []string) {
+	id, _ := strconv.ParseInt(args[0], 10, 64)
+	var req github.UpdateCheckRunOptions
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if !validCheckRunOutput(w, req.Output) {
+		return
+	}
+	for _, run := range s.checkRuns {
+		if run.ID != id {
+			continue
+		}
+		if req.Status != nil {
+			run.Status = *req.Status
+		}
+		if req.Conclusion != nil {
+			run.Conclusion = *req.Conclusion
+		}
+		run.applyOutput(req.Output)
+		writeJSON(w, http.StatusOK, run.checkRun())
+		return
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+func (s *Server) getPull(w http.ResponseWriter, r *http.Request, args []string) {
+	number, _ := strconv.Atoi(args[0])
+	pull, ok := s.pulls[number]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, pull)
+}
+
+func (s *Server) listPullsWithCommit(w http.ResponseWriter, r *http.Request, args []string) {
+	pulls := []*github.PullRequest{}
+	for _, number := range s.pullNumbers() {
+		// Like GitHub, this includes the pull requests that merely contain the commit
+		if pull := s.pulls[number]; pull.GetHead().GetSHA() == args[0] || slices.Contains(s.commits[number], args[0]) {
+			pulls = append(pulls, pull)
+		}
+	}
+	writeJSON(w, http.StatusOK, pulls)
+}
+
+func (s *Server) listPulls(w http.ResponseWriter, r *http.Request, args []string) {
+	state, head := r.URL.Query().Get("state"), r.URL.Query().Get("head")
+	if state == "" {
+		state = "open"
+	}
+	pulls := []*github.PullRequest{}
+	for _, number := range s.pullNumbers() {
+		pull := s.pulls[number]
+		if (state == "all" || pull.GetState() == state) && (head == "" || pull.GetHead().GetLabel() == head) {
+			pulls = append(pulls, pull)
+		}
+	}
+	writeJSON(w, http.StatusOK, pulls)
+}
+
+// pullNumbers returns the numbers of the pull requests in order
+func (s *Server) pullNumbers() []int {
+	numbers := make([]int, 0, len(s.pulls))
+	for number := range s.pulls {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+func (s *Server) listFiles(w http.ResponseWriter, r *http.Request, args []string) {
+	number, _ := strconv.Atoi(args[0])
+	files := s.files[number]
+	if files == nil {
+		files = []*github.CommitFile{}
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (s *Server) createReview(w http.ResponseWriter, r *http.Request, args []string) {
+	number, _ := strconv.Atoi(args[0])
+	var req github.PullRequestReviewRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	for _, comment := range req.Comments {
+		s.threads = append(s.threads, &ReviewThread{
+			NodeID: fmt.Sprintf("PRRT_%d", s.id()),
+			Number: number,
+			Path:   comment.GetPath(),
+			Line:   comment.GetLine(),
+			Body:   comment.GetBody(),
+		})
+	}
+	writeJSON(w, http.StatusOK, &github.PullRequestReview{ID: github.Int64(s.id()), Body: req.Body, State: github.String("COMMENTED")})
+}
+
+// graphql serves the queries and mutations ghpc sends, told apart by the field they select
+func (s *Server) graphql(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.Contains(req.Query, "minimizeComment"):
+		c := s.commentByNodeID(fmt.Sprint(req.Variables["id"]))
+		if c == nil {
+			writeGraphQLError(w, "Could not resolve to a node with the global id")
+			return
+		}
+		c.IsMinimized = true
+		c.MinimizedReason = "outdated"
+		writeGraphQL(w, map[string]interface{}{
+			"minimizeComment": map[string]interface{}{
+				"minimizedComment": map[string]interface{}{"isMinimized": true, "minimizedReason": c.MinimizedReason},
+			},
+		})
+	case strings.Contains(req.Query, "updateIssueComment"):
+		c := s.commentByNodeID(fmt.Sprint(req.Variables["id"]))
+		if c == nil {
+			writeGraphQLError(w, "Could not resolve to a node with the global id")
+			return
+		}
+		c.Body = fmt.Sprint(req.Variables["body"])
+		writeGraphQL(w, map[string]interface{}{
+			"updateIssueComment": map[string]interface{}{"issueComment": map[string]interface{}{"body": c.Body}},
+		})
+	case strings.Contains(req.Query, "resolveReviewThread"):
+		id := fmt.Sprint(req.Variables["id"])
+		for _, t := range s.threads {
+			if t.NodeID == id {
+				t.IsResolved = true
+				writeGraphQL(w, map[string]interface{}{
+					"resolveReviewThread": map[string]interface{}{"thread": map[string]interface{}{"id": id, "isResolved": true}},
+				})
+				return
+			}
+		}
+		writeGraphQLError(w, "Could not resolve to a node with the global id")
+	case strings.Contains(req.Query, "reviewThreads"):
+		number, _ := req.Variables["number"].(float64)
+		var threads []*ReviewThread
+		for _, t := range s.threads {
+			if t.Number == int(number) {
+				threads = append(threads, t)
+			}
+		}
+		// Cursors are the index of the thread the next page starts at
+		first := len(threads)
+		if m := firstPattern.FindStringSubmatch(req.Query); m != nil {
+			first, _ = strconv.Atoi(m[1])
+		}
+		start := 0
+		if cursor, ok := req.Variables["cursor"].(string); ok {
+			start, _ = strconv.Atoi(cursor)
+		}
+		end := start + first
+		if end > len(threads) {
+			end = len(threads)
+		}
+		nodes := []interface{}{}
+		for _, t := range threads[start:end] {
+			nodes = append(nodes, map[string]interface{}{
+				"id":         t.NodeID,
+				"isResolved": t.IsResolved,
+				"comments":   map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"body": t.Body}}},
+			})
+		}
+		writeGraphQL(w, map[string]interface{}{
+			"repository": map[string]interface{}{
+				"pullRequest": map[string]interface{}{
+					"reviewThreads": map[string]interface{}{
+						"pageInfo": map[string]interface{}{"hasNextPage": end < len(threads), "endCursor": strconv.Itoa(end)},
+						"nodes":    nodes,
+					},
+				},
+			},
+		})
+	default:
+		writeGraphQLError(w, "unsupported query")
+	}
+}
+
+func (s *Server) commentByNodeID(nodeID string) *Comment {
+	for _, c := range s.comments {
+		if c.NodeID == nodeID {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *Comment) issueComment() *github.IssueComment {
+	return &github.IssueComment{
+		ID:      github.Int64(c.ID),
+		NodeID:  github.String(c.NodeID),
+		Body:    github.String(c.Body),
+		HTMLURL: github.String(c.HTMLURL),
+	}
+}
+
+func (st *Status) repoStatus() *github.RepoStatus {
+	return &github.RepoStatus{
+		ID:          github.Int64(st.ID),
+		State:       github.String(st.State),
+		Context:     github.String(st.Context),
+		Description: github.String(st.Description),
+		TargetURL:   github.String(st.TargetURL),
+		CreatedAt:   &st.CreatedAt,
+		UpdatedAt:   &st.CreatedAt,
+	}
+}
+
+// validCheckRunOutput rejects a summary longer than GitHub accepts
+func validCheckRunOutput(w http.ResponseWriter, output *github.CheckRunOutput) bool {
+	if output != nil && len(output.GetSummary()) > 65535 {
+		writeError(w, http.StatusUnprocessableEntity, "Invalid request: only 65535 characters are allowed in output.summary")
+		return false
+	}
+	return true
+}
+
+func (run *CheckRun) applyOutput(output *github.CheckRunOutput) {
+	if output == nil {
+		return
+	}
+	if output.Title != nil {
+		run.Title = *output.Title
+	}
+	if output.Summary != nil {
+		run.Summary = *output.Summary
+	}
+	run.Annotations = append(run.Annotations, output.Annotations...)
+}
+
+func (run *CheckRun) checkRun() *github.CheckRun {
+	checkRun := &github.CheckRun{
+		ID:      github.Int64(run.ID),
+		Name:    github.String(run.Name),
+		HeadSHA: github.String(run.HeadSHA),
+		Status:  github.String(run.Status),
+	}
+	if run.Conclusion != "" {
+		checkRun.Conclusion = github.String(run.Conclusion)
+	}
+	return checkRun
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"message": message})
+}
+
+func writeGraphQL(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+func writeGraphQLError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errors": []interface{}{map[string]string{"message": message}}})
+}
@@ -6,34 +6,39 @@ import (
 	"testing"
 
 	"gh-pr-commenter/internal"
+	"gh-pr-commenter/tests/fakegithub"
 	"github.com/google/go-github/v41/github"
 	"github.com/jarcoal/httpmock"
-	"github.com/machinebox/graphql"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestUpsertComment(t *testing.T) {
-	httpmock.Activate()
-	defer httpmock.DeactivateAndReset()
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
 
 	ctx := context.Background()
-	client := github.NewClient(nil)
-	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
-
-	// Mock GitHub API responses
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
-		httpmock.NewStringResponder(200, `[]`))
-	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
-		httpmock.NewStringResponder(201, `{}`))
+	client := server.Client()
+	graphqlClient := server.GraphQLClient()
 
 	filename := "test-comment.txt"
-	content := "This is a test comment."
+	content := "This is a test comment. test-title test-identifier"
 	err := os.WriteFile(filename, []byte(content), 0644)
 	assert.NoError(t, err)
 	defer os.Remove(filename)
 
-	err = internal.UpsertComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", filename, "test-title", "test-identifier")
-	assert.NoError(t, err)
+	// The second upsert hides the first comment and marks it minimized
+	for i := 0; i < 2; i++ {
+		err = internal.UpsertComment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", filename, "test-title", "test-identifier")
+		assert.NoError(t, err)
+	}
+
+	comments := server.Comments(123)
+	if assert.Len(t, comments, 2) {
+		assert.True(t, comments[0].IsMinimized)
+		assert.Contains(t, comments[0].Body, "<!-- MINIMIZED -->")
+		assert.False(t, comments[1].IsMinimized)
+		assert.Contains(t, comments[1].Body, content)
+	}
 }
 
 func TestReadCommentFromFile(t *testing.T) {
@@ -0,0 +1,59 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"gh-pr-commenter/internal"
+	"gh-pr-commenter/pkg/status"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/google/go-github/v41/github"
+	"github.com/machinebox/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRunTransport(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	ctx := context.Background()
+	_, _, err := server.Client().Issues.CreateComment(ctx, "test-owner", "test-repo", 123, &github.IssueComment{Body: github.String("## echo output\nold <!-- Part #1 -->")})
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	httpClient := &http.Client{Transport: &internal.DryRunTransport{Base: server.Client().Client().Transport, Out: &out}}
+	client := github.NewClient(httpClient)
+	client.BaseURL, _ = client.BaseURL.Parse(server.URL() + "/")
+	graphqlClient := graphql.NewClient(server.URL()+"/graphql", graphql.WithHTTPClient(httpClient))
+
+	_, err = internal.UpsertCommentBody(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "## echo output\nnew <!-- Part #1 -->", "## echo output", "Part #1")
+	assert.NoError(t, err)
+	err = status.NewCommitStatusReporter(client, "test-owner", "test-repo", "test-commit").Report(ctx, status.Status{State: status.StateSuccess, Context: "ghpc/echo"})
+	assert.NoError(t, err)
+
+	// Reads reach GitHub, writes are only printed
+	comments := server.Comments(123)
+	if assert.Len(t, comments, 1) {
+		assert.False(t, comments[0].IsMinimized)
+		assert.NotContains(t, comments[0].Body, "<!-- MINIMIZED -->")
+	}
+	assert.Empty(t, server.Statuses("test-commit"))
+	assert.Contains(t, out.String(), "[dry-run] Would minimize comment "+comments[0].NodeID+" as outdated")
+	assert.Contains(t, out.String(), "[dry-run] Would create a comment on #123:\n## echo output\nnew <!-- Part #1 -->")
+	assert.Contains(t, out.String(), `[dry-run] Would set status "ghpc/echo" on test-commit to success: Success`)
+}
+
+func TestDryRunTransport_Offline(t *testing.T) {
+	var out bytes.Buffer
+	httpClient := &http.Client{Transport: &internal.DryRunTransport{Out: &out, Offline: true}}
+	client := github.NewClient(httpClient)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql", graphql.WithHTTPClient(httpClient))
+
+	ctx := context.Background()
+	_, err := internal.UpsertCommentBody(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "## echo output\nnew", "## echo output", "Part #1")
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "Would minimize")
+	assert.Contains(t, out.String(), "[dry-run] Would create a comment on #123:\n## echo output\nnew")
+}
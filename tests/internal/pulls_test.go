@@ -0,0 +1,44 @@
+package internal_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gh-pr-commenter/internal"
+	"gh-pr-commenter/tests/fakegithub"
+	"github.com/google/go-github/v41/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveReviewThreads_Paginates(t *testing.T) {
+	server := fakegithub.New("test-owner", "test-repo")
+	defer server.Close()
+
+	ctx := context.Background()
+	// More threads than fit on one page, with another tool's thread in between
+	var comments []*github.DraftReviewComment
+	for i := 1; i <= 150; i++ {
+		marker := "<!-- ghpc-review: tflint -->"
+		if i == 120 {
+			marker = "<!-- ghpc-review: trivy -->"
+		}
+		comments = append(comments, &github.DraftReviewComment{
+			Path: github.String("main.tf"),
+			Line: github.Int(i),
+			Body: github.String(fmt.Sprintf("Finding %d %s", i, marker)),
+		})
+	}
+	err := internal.CreateReview(ctx, server.Client(), "test-owner", "test-repo", 123, &github.PullRequestReviewRequest{Event: github.String("COMMENT"), Comments: comments})
+	assert.NoError(t, err)
+
+	err = internal.ResolveReviewThreads(ctx, server.GraphQLClient(), "test-owner", "test-repo", 123, "<!-- ghpc-review: tflint -->")
+	assert.NoError(t, err)
+
+	threads := server.ReviewThreads(123)
+	if assert.Len(t, threads, 150) {
+		for i, thread := range threads {
+			assert.Equal(t, i != 119, thread.IsResolved, "thread %d", i+1)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package comments_test
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"gh-pr-commenter/config"
@@ -64,6 +65,26 @@ func TestSplitMessage(t *testing.T) {
 	assert.Contains(t, parts[0], "This is a test message")
 }
 
+func TestRenderParts_TruncatesStreams(t *testing.T) {
+	combined := strings.Repeat("combined line\n", 6000)
+	stdout := strings.Repeat("stdout line\n", 10000)
+	stderr := strings.Repeat("stderr line\n", 10000)
+
+	parts, err := comments.RenderParts("{{ .Stdout }}\n{{ .Stderr }}\n---OUTPUT---", "make build", combined, stdout, stderr)
+	assert.NoError(t, err)
+	assert.Len(t, parts, 2)
+	for _, part := range parts {
+		assert.LessOrEqual(t, len(part), 65536)
+		assert.Contains(t, part, "stdout line\n\n... (truncated)\n\nstderr line")
+		assert.Contains(t, part, "stderr line\n\n... (truncated)\n")
+	}
+
+	// Streams that fit are left whole
+	parts, err = comments.RenderParts("{{ .Stderr }}\n---OUTPUT---", "make build", "ok\n", "ok\n", "warning\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"## make output\nwarning\n\nok\n <!-- Part #1 -->"}, parts)
+}
+
 func TestCreateDefaultTemplate(t *testing.T) {
 	filename := "test-template.md"
 	command := "test-command"
@@ -75,3 +96,139 @@ func TestCreateDefaultTemplate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, string(content), "---OUTPUT---")
 }
+
+func TestComment_ArchivesOutput(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(201, `{}`))
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TEMPLATE_FILENAME", tmpDir+"/template.md")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+	os.Setenv("KEEP_OUTPUT", "false")
+
+	filename := tmpDir + "/.output-echo.md"
+	err := os.WriteFile(filename, []byte("This is a test command output."), 0644)
+	assert.NoError(t, err)
+
+	err = comments.Comment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "echo Hello")
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filename)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(tmpDir + "/.output-echo.posted.md")
+	assert.NoError(t, err)
+	_, err = os.Stat(".comment-test-repo-123-echo-part-1.md")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestComment_KeepOutput(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	graphqlClient := graphql.NewClient("https://api.github.com/graphql")
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/issues/123/comments",
+		httpmock.NewStringResponder(201, `{}`))
+
+	tmpDir := t.TempDir()
+	os.Setenv("HEAD_COMMIT", "test-commit")
+	os.Setenv("BASE_REPO_OWNER", "test-owner")
+	os.Setenv("BASE_REPO_NAME", "test-repo")
+	os.Setenv("PULL_NUM", "123")
+	os.Setenv("GITHUB_TOKEN", "test-token")
+	os.Setenv("TEMPLATE_FILENAME", tmpDir+"/template.md")
+	os.Setenv("TMP_GHPC_DIR", tmpDir)
+	os.Setenv("KEEP_OUTPUT", "true")
+	defer os.Unsetenv("KEEP_OUTPUT")
+
+	filename := tmpDir + "/.output-echo.md"
+	err := os.WriteFile(filename, []byte("This is a test command output."), 0644)
+	assert.NoError(t, err)
+
+	err = comments.Comment(ctx, client, graphqlClient, "test-owner", "test-repo", "123", "echo Hello")
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filename)
+	assert.NoError(t, err)
+	part, err := os.ReadFile(tmpDir + "/.comment-test-repo-123-echo-part-1.md")
+	assert.NoError(t, err)
+	assert.Contains(t, string(part), "This is a test command output.")
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	tests := []struct {
+		command string
+		fenced  bool
+	}{
+		{"terraform plan", false},
+		{"tflint --format json", false},
+		{"trivy", false},
+		{"checkov -d terraform/", true},
+		{"echo trivy tflint", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.fenced, strings.Contains(comments.DefaultTemplate(tt.command), "```diff"))
+		})
+	}
+}
+
+func TestCreateDefaultTemplate_KeepsCustomTemplate(t *testing.T) {
+	filename := t.TempDir() + "/template.md"
+	custom := "<details><summary>Warnings</summary>{{ .Stderr }}</details>\n---OUTPUT---"
+	err := os.WriteFile(filename, []byte(custom), 0644)
+	assert.NoError(t, err)
+
+	err = comments.CreateDefaultTemplate(filename, "terraform plan")
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, custom, string(content))
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := comments.TemplateData{
+		Command: "terraform plan",
+		Output:  "+ resource",
+		Stdout:  "+ resource",
+		Stderr:  "Warning: deprecated",
+		Part:    1,
+		Parts:   2,
+	}
+
+	rendered, err := comments.RenderTemplate("```diff\n---OUTPUT---\n```", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "```diff\n+ resource\n```", rendered)
+
+	rendered, err = comments.RenderTemplate("{{ if .Stderr }}<details><summary>Warnings</summary>{{ .Stderr }}</details>{{ end }}\n---OUTPUT--- ({{ .Part }}/{{ .Parts }})", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "<details><summary>Warnings</summary>Warning: deprecated</details>\n+ resource (1/2)", rendered)
+
+	// Braces that don't reference a field aren't template actions
+	rendered, err = comments.RenderTemplate("Run with `${{ secrets.TOKEN }}` {{ .Missing\n---OUTPUT---", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Run with `${{ secrets.TOKEN }}` {{ .Missing\n+ resource", rendered)
+
+	_, err = comments.RenderTemplate("{{ .Stderr", data)
+	assert.Error(t, err)
+}
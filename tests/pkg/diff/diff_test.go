@@ -0,0 +1,51 @@
+package diff_test
+
+import (
+	"testing"
+
+	"gh-pr-commenter/pkg/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+const patch = `@@ -1,4 +1,5 @@
+ resource "aws_instance" "web" {
+-  ami           = "ami-1"
++  ami           = "ami-2"
++  instance_type = "t3.micro"
+   tags          = {}
+ }
+@@ -20,2 +21,3 @@ locals {
+   a = 1
++  b = 2
+ }
+\ No newline at end of file`
+
+func TestParsePatch(t *testing.T) {
+	file := diff.ParsePatch("main.tf", patch)
+	assert.Equal(t, map[int]bool{2: true, 3: true, 22: true}, file.Added)
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true, 21: true, 22: true, 23: true}, file.Commentable)
+}
+
+func TestChanges(t *testing.T) {
+	changes := diff.Changes{}
+	changes.Add(diff.ParsePatch("main.tf", patch))
+	// Binary and renamed files come without a patch
+	changes.Add(diff.ParsePatch("logo.png", ""))
+
+	assert.True(t, changes.HasFile("./main.tf"))
+	assert.True(t, changes.HasFile("logo.png"))
+	assert.False(t, changes.HasFile("variables.tf"))
+	assert.True(t, changes.HasAddedLine("main.tf", 3))
+	assert.False(t, changes.HasAddedLine("main.tf", 4))
+	assert.True(t, changes.IsCommentable("file://main.tf", 4))
+	assert.False(t, changes.IsCommentable("main.tf", 10))
+	assert.False(t, changes.IsCommentable("logo.png", 1))
+}
+
+func TestRepoPath(t *testing.T) {
+	assert.Equal(t, "main.tf", diff.RepoPath("", "./main.tf"))
+	assert.Equal(t, "modules/app/main.tf", diff.RepoPath("modules/app", "./main.tf"))
+	assert.Equal(t, "modules/shared/outputs.tf", diff.RepoPath("modules/app", "../shared/outputs.tf"))
+	assert.Equal(t, "main.tf", diff.RepoPath(".", "file://main.tf"))
+	assert.Equal(t, "/src/main.tf", diff.RepoPath("modules/app", "/src/main.tf"))
+}
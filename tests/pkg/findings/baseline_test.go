@@ -0,0 +1,75 @@
+package findings_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/pkg/findings"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	f := findings.Finding{Tool: "tflint", Rule: "aws_instance_invalid_type", File: "main.tf", Line: 12, Message: `"t1.2xlarge" is an invalid value`}
+
+	moved := f
+	moved.Line = 40
+	moved.Message = `  "t1.3xlarge"   is an invalid value`
+	assert.Equal(t, findings.Fingerprint(f), findings.Fingerprint(moved))
+
+	otherFile := f
+	otherFile.File = "web.tf"
+	assert.NotEqual(t, findings.Fingerprint(f), findings.Fingerprint(otherFile))
+
+	otherRule := f
+	otherRule.Rule = "aws_instance_previous_type"
+	assert.NotEqual(t, findings.Fingerprint(f), findings.Fingerprint(otherRule))
+}
+
+func TestBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	known := findings.Finding{Tool: "tflint", Rule: "terraform_unused_declarations", File: "variables.tf", Line: 3, Message: `variable "region" is declared but not used`}
+	fixed := findings.Finding{Tool: "tflint", Rule: "terraform_deprecated_interpolation", File: "main.tf", Line: 4, Message: "Interpolation-only expressions are deprecated"}
+	added := findings.Finding{Tool: "tflint", Rule: "aws_instance_invalid_type", File: "main.tf", Line: 12, Message: "invalid value"}
+
+	// A missing baseline file is empty
+	baseline, err := findings.LoadBaseline(path)
+	assert.NoError(t, err)
+	assert.Empty(t, baseline.Kinds)
+
+	baseline.Record("tflint", []findings.Finding{known, fixed, known})
+	baseline.Record("trivy", []findings.Finding{{Tool: "trivy", Rule: "AVD-AWS-0086", File: "s3.tf"}})
+	assert.NoError(t, baseline.Save(path))
+
+	loaded, err := findings.LoadBaseline(path)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.Kinds["tflint"], 2)
+	assert.Equal(t, "main.tf", loaded.Kinds["tflint"][0].File)
+
+	known.Line = 5
+	compared := loaded.Compare("tflint", []findings.Finding{known, added})
+	assert.Equal(t, []findings.Finding{added}, compared.New)
+	assert.Equal(t, 1, compared.Fixed)
+	assert.Equal(t, 1, compared.Known)
+
+	// Each kind is compared with its own entries only
+	compared = loaded.Compare("sarif", []findings.Finding{known})
+	assert.Equal(t, []findings.Finding{known}, compared.New)
+	assert.Equal(t, 0, compared.Fixed)
+}
+
+func TestCompare(t *testing.T) {
+	fixed := findings.Finding{Tool: "trivy", Rule: "CVE-2024-1", Severity: "medium", File: "go.sum", Message: "old"}
+	unchanged := findings.Finding{Tool: "trivy", Rule: "CVE-2024-2", Severity: "low", File: "go.sum", Message: "kept"}
+	added := []findings.Finding{
+		{Tool: "trivy", Rule: "CVE-2024-3", Severity: "high", File: "go.sum", Message: "new"},
+		{Tool: "trivy", Rule: "CVE-2024-4", Severity: "high", File: "go.sum", Message: "new"},
+	}
+
+	delta := findings.Compare([]findings.Finding{fixed, unchanged}, append([]findings.Finding{unchanged}, added...))
+	assert.Equal(t, added, delta.New)
+	assert.Equal(t, []findings.Finding{fixed}, delta.Fixed)
+	assert.Equal(t, 1, delta.Unchanged)
+	assert.Equal(t, "2 new (2 high), 1 fixed (1 medium), 1 unchanged", delta.String())
+
+	assert.Equal(t, "0 new, 0 fixed, 1 unchanged", findings.Compare([]findings.Finding{unchanged}, []findings.Finding{unchanged}).String())
+}
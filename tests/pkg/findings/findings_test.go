@@ -0,0 +1,103 @@
+package findings_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gh-pr-commenter/pkg/findings"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTflintJSON(t *testing.T) {
+	data, err := os.ReadFile("testdata/tflint.json")
+	assert.NoError(t, err)
+
+	parsed, err := findings.ParseTflintJSON(data)
+	assert.NoError(t, err)
+	assert.Len(t, parsed, 3)
+	assert.Equal(t, findings.Finding{
+		Tool:     "tflint",
+		Rule:     "aws_instance_invalid_type",
+		Severity: "error",
+		File:     "main.tf",
+		Line:     12,
+		Message:  `"t1.2xlarge" is an invalid value as instance_type`,
+	}, parsed[1])
+
+	_, err = findings.ParseTflintJSON([]byte("main.tf:12 error"))
+	assert.Error(t, err)
+}
+
+func TestParsePolicy(t *testing.T) {
+	warning := findings.Finding{Severity: "warning"}
+	notice := findings.Finding{Severity: "notice"}
+	critical := findings.Finding{Severity: "critical"}
+	high := findings.Finding{Severity: "high"}
+	low := findings.Finding{Severity: "low"}
+
+	policy, err := findings.ParsePolicy("")
+	assert.NoError(t, err)
+	assert.True(t, policy.Fails(notice))
+
+	policy, err = findings.ParsePolicy("error,warning")
+	assert.NoError(t, err)
+	assert.True(t, policy.Fails(warning))
+	assert.False(t, policy.Fails(notice))
+
+	policy, err = findings.ParsePolicy("CRITICAL,HIGH")
+	assert.NoError(t, err)
+	assert.True(t, policy.Fails(critical))
+	assert.True(t, policy.Fails(high))
+	assert.False(t, policy.Fails(low))
+	assert.Len(t, policy.Failing([]findings.Finding{critical, low, high}), 2)
+
+	_, err = findings.ParsePolicy("SEVERE")
+	assert.Error(t, err)
+}
+
+func TestSummary(t *testing.T) {
+	assert.Equal(t, "no issues", findings.Summary(nil))
+	assert.Equal(t, "1 issue (1 error)", findings.Summary([]findings.Finding{{Severity: "error"}}))
+	assert.Equal(t, "3 issues (1 error, 2 warning)", findings.Summary([]findings.Finding{
+		{Severity: "warning"}, {Severity: "error"}, {Severity: "warning"},
+	}))
+}
+
+func TestMarkdown(t *testing.T) {
+	data, err := os.ReadFile("testdata/tflint.json")
+	assert.NoError(t, err)
+	parsed, err := findings.ParseTflintJSON(data)
+	assert.NoError(t, err)
+
+	markdown := findings.Markdown("tflint", parsed)
+	assert.Contains(t, markdown, "#### tflint: 3 issues (1 error, 1 warning, 1 notice)")
+	assert.Contains(t, markdown, "<details open><summary><code>main.tf</code> (2)</summary>")
+	assert.Contains(t, markdown, "| 4 | notice | terraform_deprecated_interpolation | Interpolation-only expressions are deprecated |\n| 12 | error |")
+	assert.Less(t, strings.Index(markdown, "main.tf"), strings.Index(markdown, "variables.tf"))
+}
+
+func TestParseSARIF(t *testing.T) {
+	data, err := os.ReadFile("testdata/checkov.sarif")
+	assert.NoError(t, err)
+
+	parsed, err := findings.ParseSARIF(data)
+	assert.NoError(t, err)
+	assert.Len(t, parsed, 3)
+	assert.Equal(t, findings.Finding{
+		Tool:     "checkov",
+		Rule:     "CKV_AWS_18",
+		Severity: "high",
+		File:     "modules/s3/main.tf",
+		Line:     7,
+		Message:  "Ensure the S3 bucket has access logging enabled",
+	}, parsed[0])
+	// Falls back to the rule's default level
+	assert.Equal(t, findings.Severity("note"), parsed[1].Severity)
+	assert.Equal(t, findings.Finding{Tool: "gosec", Rule: "G104", Severity: "warning", Message: "Errors unhandled."}, parsed[2])
+
+	assert.Equal(t, []string{"checkov", "gosec"}, findings.Tools(parsed))
+
+	_, err = findings.ParseSARIF([]byte(`{"version":"1.0.0","runs":[]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package junit_test
+
+import (
+	"os"
+	"testing"
+
+	"gh-pr-commenter/pkg/junit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	data, err := os.ReadFile("testdata/report.xml")
+	assert.NoError(t, err)
+
+	suites, err := junit.Parse(data)
+	assert.NoError(t, err)
+	assert.Len(t, suites, 2)
+	assert.Equal(t, "gh-pr-commenter/pkg/comments", suites[0].Name)
+	assert.Equal(t, junit.Counts{Passed: 1, Failed: 1, Skipped: 1}, suites[0].Counts())
+	assert.Equal(t, junit.Counts{Passed: 1, Failed: 1}, suites[1].Counts())
+	assert.Equal(t, "2 passed, 2 failed, 1 skipped", junit.Total(suites).String())
+}
+
+func TestParse_SingleSuite(t *testing.T) {
+	suites, err := junit.Parse([]byte(`<testsuite name="unit"><testcase name="a"/><testcase name="b"/></testsuite>`))
+	assert.NoError(t, err)
+	assert.Len(t, suites, 1)
+	assert.Equal(t, junit.Counts{Passed: 2}, junit.Total(suites))
+
+	_, err = junit.Parse([]byte(`<html></html>`))
+	assert.Error(t, err)
+	_, err = junit.Parse([]byte(`not xml`))
+	assert.Error(t, err)
+}
+
+func TestMarkdown(t *testing.T) {
+	data, err := os.ReadFile("testdata/report.xml")
+	assert.NoError(t, err)
+	suites, err := junit.Parse(data)
+	assert.NoError(t, err)
+
+	markdown := junit.Markdown(suites)
+	assert.Contains(t, markdown, "#### JUnit: 2 passed, 2 failed, 1 skipped")
+	assert.Contains(t, markdown, "| gh-pr-commenter/pkg/comments | 1 | 1 | 1 | 0.01s |")
+	assert.Contains(t, markdown, "<details><summary>gh-pr-commenter/pkg/comments: <code>comments.TestComment</code> - expected no error</summary>")
+	assert.Contains(t, markdown, "error upserting comment\n```")
+	assert.Contains(t, markdown, "<code>status.TestPanics</code> - panic: index out of range</summary>")
+	assert.NotContains(t, markdown, "TestRender")
+}
@@ -0,0 +1,124 @@
+package output_test
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"testing"
+
+	"gh-pr-commenter/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer_InMemory(t *testing.T) {
+	buf := output.NewBuffer(1024, t.TempDir())
+	defer buf.Close()
+
+	_, err := buf.Write([]byte("hello "))
+	assert.NoError(t, err)
+	_, err = buf.Write([]byte("world"))
+	assert.NoError(t, err)
+
+	assert.False(t, buf.Spilled())
+	assert.Equal(t, int64(11), buf.Len())
+	content, err := buf.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", content)
+}
+
+func TestBuffer_SpillsToDisk(t *testing.T) {
+	dir := t.TempDir()
+	buf := output.NewBuffer(8, dir)
+
+	_, err := buf.Write([]byte("12345"))
+	assert.NoError(t, err)
+	_, err = buf.Write([]byte("67890"))
+	assert.NoError(t, err)
+	_, err = buf.Write([]byte("abc"))
+	assert.NoError(t, err)
+
+	assert.True(t, buf.Spilled())
+	content, err := buf.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890abc", content)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.NoError(t, buf.Close())
+	entries, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestTimestampWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := output.NewTimestampWriter(&out)
+
+	_, err := w.Write([]byte("first\nsec"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("ond\n"))
+	assert.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{3} first\n\d{2}:\d{2}:\d{2}\.\d{3} second\n$`), out.String())
+}
+
+func TestNormalize(t *testing.T) {
+	raw := "\x1b[1mPlan:\x1b[0m done\r\n" +
+		"\x1b]0;window title\x07Downloading 10%\rDownloading 100%\n" +
+		"\x1b[32m+\x1b[0m resource\n" +
+		"  \x1b[31m-\x1b[0m resource\n" +
+		"\x1b[32mcreated\x1b[0m\n"
+
+	assert.Equal(t, "Plan: done\nDownloading 100%\n+ resource\n  - resource\ncreated\n",
+		output.Normalize(raw, output.NormalizeOptions{}))
+	assert.Equal(t, "Plan: done\nDownloading 100%\n+ resource\n-   resource\n+ created\n",
+		output.Normalize(raw, output.NormalizeOptions{ColorToDiff: true}))
+}
+
+func TestNormalize_CarriageReturnOverlay(t *testing.T) {
+	assert.Equal(t, "xycdef", output.Normalize("abcdef\rxy", output.NormalizeOptions{}))
+	assert.Equal(t, "done", output.Normalize("working...\rdone      ", output.NormalizeOptions{}))
+}
+
+func TestNormalizeWriter_MatchesNormalize(t *testing.T) {
+	raw := "\x1b[1mPlan:\x1b[0m done\r\n" +
+		"\x1b]0;window title\x07Downloading 10%\rDownloading 100%\n" +
+		"\x1b[32m+\x1b[0m resource\n" +
+		"last line without newline"
+	opts := output.NormalizeOptions{ColorToDiff: true}
+
+	// Written in small chunks, so lines and escape sequences are split across writes
+	var out bytes.Buffer
+	w := output.NewNormalizeWriter(&out, opts)
+	for i := 0; i < len(raw); i += 3 {
+		end := i + 3
+		if end > len(raw) {
+			end = len(raw)
+		}
+		_, err := w.Write([]byte(raw[i:end]))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, output.Normalize(raw, opts), out.String())
+}
+
+func TestScanNormalized(t *testing.T) {
+	// The buffer spills to disk, which is scanned without reading it into memory
+	buf := output.NewBuffer(4, t.TempDir())
+	defer buf.Close()
+	for _, chunk := range []string{"tests ", "pas", "\x1b[1msed\x1b[0m\n"} {
+		_, err := buf.Write([]byte(chunk))
+		assert.NoError(t, err)
+	}
+	empty, found, err := output.ScanNormalized(buf, output.NormalizeOptions{}, "passed")
+	assert.NoError(t, err)
+	assert.False(t, empty)
+	assert.True(t, found)
+
+	empty, found, err = output.ScanNormalized(output.NewBuffer(4, t.TempDir()), output.NormalizeOptions{}, "passed")
+	assert.NoError(t, err)
+	assert.True(t, empty)
+	assert.False(t, found)
+}
@@ -0,0 +1,200 @@
+package status_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"gh-pr-commenter/pkg/status"
+	"github.com/google/go-github/v41/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseState(t *testing.T) {
+	for _, state := range status.States {
+		parsed, err := status.ParseState(string(state))
+		assert.NoError(t, err)
+		assert.Equal(t, state, parsed)
+		assert.NotEmpty(t, state.DefaultDescription())
+	}
+	for _, invalid := range []string{"", "skipped", "SUCCESS"} {
+		_, err := status.ParseState(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCommitStatusReporter(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var posted []github.RepoStatus
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var s github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+				return nil, err
+			}
+			posted = append(posted, s)
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	ctx := context.Background()
+	reporter := status.NewCommitStatusReporter(github.NewClient(nil), "test-owner", "test-repo", "test-commit")
+
+	for _, state := range status.States {
+		assert.NoError(t, reporter.Report(ctx, status.Status{State: state, Context: "ghpc/tflint"}))
+	}
+	assert.Len(t, posted, len(status.States))
+	for i, state := range status.States {
+		assert.Equal(t, string(state), posted[i].GetState())
+		assert.Equal(t, state.DefaultDescription(), posted[i].GetDescription())
+		assert.Equal(t, "ghpc/tflint", posted[i].GetContext())
+		assert.Nil(t, posted[i].TargetURL)
+	}
+
+	err := reporter.Report(ctx, status.Status{State: status.StateFailure, Context: "ghpc/tflint", Description: strings.Repeat("é", 200), TargetURL: "https://example.com/run/1"})
+	assert.NoError(t, err)
+	last := posted[len(posted)-1]
+	assert.Equal(t, "https://example.com/run/1", last.GetTargetURL())
+	assert.Equal(t, status.MaxDescriptionLength, len([]rune(last.GetDescription())))
+	assert.True(t, strings.HasSuffix(last.GetDescription(), "…"))
+
+	// Invalid statuses are rejected before anything is posted
+	assert.Error(t, reporter.Report(ctx, status.Status{Context: "ghpc/tflint"}))
+	assert.Error(t, reporter.Report(ctx, status.Status{State: "skipped", Context: "ghpc/tflint"}))
+	assert.Error(t, reporter.Report(ctx, status.Status{State: status.StateSuccess}))
+	assert.Len(t, posted, len(status.States)+1)
+}
+
+func TestCheckRunReporter(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var created []github.CreateCheckRunOptions
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/check-runs",
+		func(req *http.Request) (*http.Response, error) {
+			var opts github.CreateCheckRunOptions
+			if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+				return nil, err
+			}
+			created = append(created, opts)
+			return httpmock.NewStringResponse(201, fmt.Sprintf(`{"id": %d}`, len(created))), nil
+		})
+	var updated []string
+	var updates []github.UpdateCheckRunOptions
+	httpmock.RegisterResponder("PATCH", `=~^https://api.github.com/repos/test-owner/test-repo/check-runs/\d+$`,
+		func(req *http.Request) (*http.Response, error) {
+			var opts github.UpdateCheckRunOptions
+			if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+				return nil, err
+			}
+			updated = append(updated, req.URL.Path)
+			updates = append(updates, opts)
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+
+	ctx := context.Background()
+	reporter := status.NewCheckRunReporter(github.NewClient(nil), "test-owner", "test-repo", "test-commit")
+
+	expected := map[status.State][2]string{
+		status.StatePending: {"in_progress", ""},
+		status.StateSuccess: {"completed", "success"},
+		status.StateFailure: {"completed", "failure"},
+		status.StateError:   {"completed", "action_required"},
+	}
+	for _, state := range status.States {
+		assert.NoError(t, reporter.Report(ctx, status.Status{State: state, Context: "ghpc/" + string(state)}))
+	}
+	assert.Len(t, created, len(status.States))
+	for i, state := range status.States {
+		assert.Equal(t, "ghpc/"+string(state), created[i].Name)
+		assert.Equal(t, expected[state][0], created[i].GetStatus())
+		assert.Equal(t, expected[state][1], created[i].GetConclusion())
+	}
+
+	// A pending check run is completed by the context's next status
+	assert.NoError(t, reporter.Report(ctx, status.Status{State: status.StateFailure, Context: "ghpc/pending", Summary: "details"}))
+	assert.Len(t, created, len(status.States))
+	assert.Equal(t, []string{"/repos/test-owner/test-repo/check-runs/1"}, updated)
+	assert.Equal(t, "failure", updates[0].GetConclusion())
+	assert.Equal(t, "details", updates[0].Output.GetSummary())
+
+	// Annotations beyond the first 50 are added in further requests
+	annotations := make([]*github.CheckRunAnnotation, 120)
+	for i := range annotations {
+		annotations[i] = &github.CheckRunAnnotation{Path: github.String("main.tf"), StartLine: github.Int(i + 1), EndLine: github.Int(i + 1), AnnotationLevel: github.String("warning"), Message: github.String("issue")}
+	}
+	assert.NoError(t, reporter.Report(ctx, status.Status{State: status.StateFailure, Context: "ghpc/tflint", Annotations: annotations}))
+	assert.Len(t, created[len(created)-1].Output.Annotations, 50)
+	assert.Len(t, updates, 3)
+	assert.Len(t, updates[1].Output.Annotations, 50)
+	assert.Len(t, updates[2].Output.Annotations, 20)
+
+	assert.Error(t, reporter.Report(ctx, status.Status{State: "", Context: "ghpc/tflint"}))
+}
+
+func TestLinkCommitStatus(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/test-owner/test-repo/commits/test-commit/statuses",
+		httpmock.NewStringResponder(200, `[
+			{"context": "ghpc/trivy", "state": "success", "description": "trivy: no issues"},
+			{"context": "ghpc/tflint", "state": "failure", "description": "tflint: 1 issue (1 error)", "target_url": "https://example.com/run/1"},
+			{"context": "ghpc/tflint", "state": "pending", "description": "In Progress"}
+		]`))
+	var posted []github.RepoStatus
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/test-owner/test-repo/statuses/test-commit",
+		func(req *http.Request) (*http.Response, error) {
+			var s github.RepoStatus
+			if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+				return nil, err
+			}
+			posted = append(posted, s)
+			return httpmock.NewStringResponse(201, `{}`), nil
+		})
+
+	ctx := context.Background()
+	client := github.NewClient(nil)
+	commentURL := "https://github.com/test-owner/test-repo/pull/123#issuecomment-1"
+	assert.NoError(t, status.LinkCommitStatus(ctx, client, "test-owner", "test-repo", "test-commit", "ghpc/tflint", commentURL))
+	assert.Len(t, posted, 1)
+	assert.Equal(t, "failure", posted[0].GetState())
+	assert.Equal(t, "tflint: 1 issue (1 error)", posted[0].GetDescription())
+	assert.Equal(t, commentURL, posted[0].GetTargetURL())
+
+	// Contexts without a status are left alone
+	assert.NoError(t, status.LinkCommitStatus(ctx, client, "test-owner", "test-repo", "test-commit", "ghpc/checkov", commentURL))
+	assert.Len(t, posted, 1)
+}
+
+func TestRenderDescription(t *testing.T) {
+	data := status.DescriptionData{State: "failure", Command: "tflint", Project: "network", Workspace: "prod", Summary: "tflint: 3 issues (1 error, 2 warning)"}
+
+	description, err := status.RenderDescription("", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "tflint: 3 issues (1 error, 2 warning)", description)
+
+	description, err = status.RenderDescription("{{.Project}}/{{.Workspace}} {{.Summary}}", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "network/prod tflint: 3 issues (1 error, 2 warning)", description)
+
+	_, err = status.RenderDescription("{{.Project", data)
+	assert.Error(t, err)
+}
+
+func TestTruncateSummary(t *testing.T) {
+	assert.Equal(t, "short", status.TruncateSummary("short", "truncated"))
+
+	// Multi-byte characters are counted in bytes and never split
+	long := strings.Repeat("é", status.MaxSummaryLength)
+	truncated := status.TruncateSummary(long, "truncated")
+	assert.LessOrEqual(t, len(truncated), status.MaxSummaryLength)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "…\n\ntruncated"))
+}
@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gh-pr-commenter/pkg/findings"
+	"gh-pr-commenter/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.Open(dir)
+	assert.NoError(t, err)
+
+	key := store.Key{SHA: "abc123", Kind: "trivy", Project: "atlantis-default"}
+	_, err = s.Load(key)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	results := []findings.Finding{{Tool: "trivy", Rule: "CVE-2024-1", Severity: "high", File: "go.sum", Message: "bad"}}
+	assert.NoError(t, s.Save(key, results))
+	assert.FileExists(t, filepath.Join(dir, "abc123", "trivy-atlantis-default.json"))
+
+	loaded, err := s.Load(key)
+	assert.NoError(t, err)
+	assert.Equal(t, results, loaded)
+
+	// Results are kept apart per project
+	_, err = s.Load(store.Key{SHA: "abc123", Kind: "trivy"})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	s, err = store.Open("file://" + dir)
+	assert.NoError(t, err)
+	loaded, err = s.Load(key)
+	assert.NoError(t, err)
+	assert.Equal(t, results, loaded)
+}
+
+type memoryStore map[store.Key][]findings.Finding
+
+func (m memoryStore) Save(key store.Key, results []findings.Finding) error {
+	m[key] = results
+	return nil
+}
+
+func (m memoryStore) Load(key store.Key) ([]findings.Finding, error) {
+	results, ok := m[key]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return results, nil
+}
+
+func TestOpen(t *testing.T) {
+	_, err := store.Open("s3://bucket/results")
+	assert.Error(t, err)
+
+	memory := memoryStore{}
+	store.Register("memory", func(location string) (store.Store, error) {
+		return memory, nil
+	})
+	s, err := store.Open("memory://results")
+	assert.NoError(t, err)
+	assert.NoError(t, s.Save(store.Key{SHA: "abc123", Kind: "tflint"}, nil))
+	assert.Len(t, memory, 1)
+}
@@ -0,0 +1,63 @@
+package terraform_test
+
+import (
+	"os"
+	"testing"
+
+	"gh-pr-commenter/pkg/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardViolations_Patterns(t *testing.T) {
+	output, err := os.ReadFile("testdata/plan.txt")
+	assert.NoError(t, err)
+	plan, err := terraform.ParsePlan(string(output))
+	assert.NoError(t, err)
+
+	guard := terraform.Guard{Patterns: []string{"aws_db_instance.*", "aws_iam_role.*", "aws_instance.*"}}
+	violations := guard.Violations(plan)
+
+	// aws_instance.web is only created, and the role is matched without its module path
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "aws_db_instance.main", violations[0].Address)
+	assert.Equal(t, "module.legacy.aws_iam_role.old", violations[1].Address)
+
+	assert.Empty(t, terraform.Guard{}.Violations(plan))
+}
+
+func TestGuardViolations_PreventDestroyTag(t *testing.T) {
+	planJSON := `{"format_version":"1.2","resource_changes":[
+{"address":"aws_s3_bucket.state","type":"aws_s3_bucket","change":{"actions":["delete"],"before":{"tags":{"prevent_destroy":"true"}},"after":null}},
+{"address":"aws_s3_bucket.tmp","type":"aws_s3_bucket","change":{"actions":["delete"],"before":{"tags":{"env":"dev"}},"after":null}}
+]}`
+	plan, err := terraform.ParsePlan(planJSON)
+	assert.NoError(t, err)
+	violations := terraform.Guard{}.Violations(plan)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "aws_s3_bucket.state", violations[0].Address)
+
+	planText := `  # aws_s3_bucket.state will be destroyed
+  - resource "aws_s3_bucket" "state" {
+      - tags = {
+          - "prevent_destroy" = "true"
+        } -> null
+    }
+
+Plan: 0 to add, 0 to change, 1 to destroy.
+`
+	plan, err = terraform.ParsePlan(planText)
+	assert.NoError(t, err)
+	assert.Len(t, terraform.Guard{}.Violations(plan), 1)
+}
+
+func TestGuardBanner(t *testing.T) {
+	violations := []terraform.ResourceChange{{Address: "aws_db_instance.main", Action: terraform.ActionReplace}}
+
+	banner := terraform.GuardBanner(violations, "ghpc-allow-destroy", false)
+	assert.Contains(t, banner, "> [!WARNING]")
+	assert.Contains(t, banner, "`aws_db_instance.main` will be replaced")
+	assert.Contains(t, banner, "Add the `ghpc-allow-destroy` label")
+
+	banner = terraform.GuardBanner(violations, "ghpc-allow-destroy", true)
+	assert.Contains(t, banner, "label is present, so the change is allowed")
+}
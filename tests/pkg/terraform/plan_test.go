@@ -0,0 +1,106 @@
+package terraform_test
+
+import (
+	"os"
+	"testing"
+
+	"gh-pr-commenter/pkg/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlanText(t *testing.T) {
+	output, err := os.ReadFile("testdata/plan.txt")
+	assert.NoError(t, err)
+
+	plan, err := terraform.ParsePlan(string(output))
+	assert.NoError(t, err)
+	assert.Len(t, plan.Resources, 4)
+
+	assert.Equal(t, "aws_db_instance.main", plan.Resources[0].Address)
+	assert.Equal(t, "aws_db_instance", plan.Resources[0].Type)
+	assert.Equal(t, terraform.ActionReplace, plan.Resources[0].Action)
+	assert.Equal(t, terraform.ActionCreate, plan.Resources[1].Action)
+	assert.Contains(t, plan.Resources[1].Diff, `"Name" = "web"`)
+	assert.Equal(t, terraform.ActionUpdate, plan.Resources[2].Action)
+	assert.Equal(t, "module.legacy.aws_iam_role.old", plan.Resources[3].Address)
+	assert.Equal(t, "aws_iam_role", plan.Resources[3].Type)
+	assert.Equal(t, terraform.ActionDelete, plan.Resources[3].Action)
+
+	assert.Equal(t, "+2 ~1 -2", plan.StatusDescription())
+}
+
+func TestParsePlanText_ResourceTypes(t *testing.T) {
+	output := `Terraform will perform the following actions:
+
+  # data.aws_ami.ubuntu will be read during apply
+ <= data "aws_ami" "ubuntu" {
+    }
+
+  # module.a.data.aws_iam_policy.p will be read during apply
+ <= data "aws_iam_policy" "p" {
+    }
+
+  # module.a.module.b.aws_s3_bucket.logs will be created
+  + resource "aws_s3_bucket" "logs" {
+    }
+
+Plan: 1 to add, 0 to change, 0 to destroy.
+`
+	plan, err := terraform.ParsePlan(output)
+	assert.NoError(t, err)
+	if assert.Len(t, plan.Resources, 3) {
+		assert.Equal(t, "aws_ami", plan.Resources[0].Type)
+		assert.Equal(t, terraform.ActionRead, plan.Resources[0].Action)
+		assert.Equal(t, "aws_iam_policy", plan.Resources[1].Type)
+		assert.Equal(t, "aws_s3_bucket", plan.Resources[2].Type)
+	}
+}
+
+func TestParsePlanJSON(t *testing.T) {
+	output, err := os.ReadFile("testdata/plan.json")
+	assert.NoError(t, err)
+
+	plan, err := terraform.ParsePlan(string(output))
+	assert.NoError(t, err)
+	assert.Len(t, plan.Resources, 3)
+
+	assert.Equal(t, terraform.ActionCreate, plan.Resources[0].Action)
+	assert.Contains(t, plan.Resources[0].Diff, `+ ami = "ami-123456"`)
+	assert.Contains(t, plan.Resources[0].Diff, "+ id = (known after apply)")
+	assert.Equal(t, terraform.ActionReplace, plan.Resources[1].Action)
+	assert.Contains(t, plan.Resources[1].Diff, `- engine_version = "13.4"`)
+	assert.Contains(t, plan.Resources[1].Diff, `+ engine_version = "14.1"`)
+	assert.Equal(t, "old", plan.Resources[2].Before["name"])
+
+	assert.Equal(t, "+2 ~0 -2", plan.StatusDescription())
+}
+
+func TestParsePlan_NoChanges(t *testing.T) {
+	plan, err := terraform.ParsePlan("No changes. Your infrastructure matches the configuration.\n")
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Resources)
+	assert.Equal(t, "+0 ~0 -0", plan.StatusDescription())
+	assert.Contains(t, plan.Markdown(), "No changes.")
+}
+
+func TestParsePlan_NotAPlan(t *testing.T) {
+	_, err := terraform.ParsePlan("Terraform has been successfully initialized!\n")
+	assert.ErrorIs(t, err, terraform.ErrNoPlan)
+}
+
+func TestPlanMarkdown(t *testing.T) {
+	output, err := os.ReadFile("testdata/plan.txt")
+	assert.NoError(t, err)
+	plan, err := terraform.ParsePlan(string(output))
+	assert.NoError(t, err)
+
+	markdown := plan.Markdown()
+	assert.Contains(t, markdown, "#### Terraform plan: 2 to add, 1 to change, 2 to destroy")
+	assert.Contains(t, markdown, "> [!CAUTION]")
+	assert.Contains(t, markdown, "> - `aws_db_instance.main` will be replaced")
+	assert.Contains(t, markdown, "<details open><summary><code>module.legacy.aws_iam_role.old</code> will be destroyed</summary>")
+	assert.Contains(t, markdown, "<details><summary><code>aws_instance.web</code> will be created</summary>")
+	assert.Contains(t, markdown, "\n+   resource \"aws_instance\" \"web\" {")
+	assert.Contains(t, markdown, "\n!   resource \"aws_s3_bucket\" \"logs\" {")
+	assert.Contains(t, markdown, "\n!   resource \"aws_db_instance\" \"main\" {")
+}
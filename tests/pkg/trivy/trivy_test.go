@@ -0,0 +1,87 @@
+package trivy_test
+
+import (
+	"os"
+	"testing"
+
+	"gh-pr-commenter/pkg/findings"
+	"gh-pr-commenter/pkg/trivy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReport(t *testing.T) {
+	data, err := os.ReadFile("testdata/report.json")
+	assert.NoError(t, err)
+
+	report, err := trivy.ParseReport(data)
+	assert.NoError(t, err)
+	assert.Len(t, report.Results, 3)
+	// Passed checks are dropped
+	assert.Len(t, report.Results[0].Misconfigurations, 1)
+
+	all := report.Findings()
+	assert.Len(t, all, 3)
+	assert.Equal(t, findings.Finding{
+		Tool:     "trivy",
+		Rule:     "AVD-AWS-0086",
+		Severity: "high",
+		File:     "main.tf",
+		Line:     3,
+		Message:  "No public access block so not blocking public acls",
+	}, all[0])
+	assert.Equal(t, findings.Severity("critical"), all[1].Severity)
+
+	_, err = trivy.ParseReport([]byte(`{"issues":[]}`))
+	assert.Error(t, err)
+}
+
+func TestReportMarkdown(t *testing.T) {
+	data, err := os.ReadFile("testdata/report.json")
+	assert.NoError(t, err)
+	report, err := trivy.ParseReport(data)
+	assert.NoError(t, err)
+
+	markdown, err := report.Markdown()
+	assert.NoError(t, err)
+	assert.Contains(t, markdown, "<h3>Target <code>main.tf</code></h3>")
+	assert.Contains(t, markdown, "<h4>TF Sec Misconfigurations</h4>")
+	assert.Contains(t, markdown, "<h4>Vulnerabilities</h4>")
+	assert.Contains(t, markdown, "<h4>Secrets</h4>")
+	assert.Contains(t, markdown, "net/netip: Unexpected behavior from Is methods for IPv4-mapped IPv6 addresses")
+	assert.NotContains(t, markdown, "Unencrypted S3 bucket")
+	assert.Contains(t, markdown, "<th>Total Findings</th>")
+	assert.Contains(t, markdown, "<td>3</td>")
+}
+
+func TestReportMarkdown_Passed(t *testing.T) {
+	report, err := trivy.ParseReport([]byte(`{"SchemaVersion":2,"Results":[{"Target":"main.tf"}]}`))
+	assert.NoError(t, err)
+	markdown, err := report.Markdown()
+	assert.NoError(t, err)
+	assert.Contains(t, markdown, "Trivy scan passed")
+
+	// A scan of nothing, e.g. an empty directory, is a valid report too
+	for _, data := range []string{`{"SchemaVersion":2,"Results":[]}`, `{"SchemaVersion":2}`} {
+		report, err = trivy.ParseReport([]byte(data))
+		assert.NoError(t, err)
+		markdown, err = report.Markdown()
+		assert.NoError(t, err)
+		assert.Contains(t, markdown, "Trivy scan passed", data)
+	}
+}
+
+func TestReportFilter_AllFilteredPasses(t *testing.T) {
+	data, err := os.ReadFile("testdata/report.json")
+	assert.NoError(t, err)
+	report, err := trivy.ParseReport(data)
+	assert.NoError(t, err)
+
+	filtered := report.Filter(func(f findings.Finding) bool { return false })
+	assert.Len(t, filtered.Results, 3)
+	assert.Empty(t, filtered.Findings())
+
+	markdown, err := filtered.Markdown()
+	assert.NoError(t, err)
+	assert.Contains(t, markdown, "Trivy scan passed")
+	assert.NotContains(t, markdown, "<h3>Target")
+}